@@ -13,16 +13,14 @@ terraform {
     }
 }
 
-// Create new namespace using Shaken Fist "system" privilege.
+// Create the lab using Shaken Fist "system" privilege. The system key is
+// read from the SHAKENFIST_KEY environment variable.
 provider "shakenfist" {
-    alias = "system"
     server_url = "http://sf-1:13000"
     namespace = "system"
-    key = "Ukoh5vie"
 }
 
 resource "shakenfist_namespace" "lab123" {
-    provider = shakenfist.system
     name = "lab123"
     metadata = {
         owner = "cloudy"
@@ -31,25 +29,31 @@ resource "shakenfist_namespace" "lab123" {
     }
 }
 
+// The student key is generated, and a new key is generated each term.
 resource "shakenfist_key" "key1" {
-    provider = shakenfist.system
     namespace = shakenfist_namespace.lab123.name
     keyname = "student"
-    key = "secretsadf32jkhsdf234dsf"
+    key_length = 24
+    rotation_triggers = {
+        term = "2026-autumn"
+    }
+}
+
+output "student_key" {
+    value = shakenfist_key.key1.key
+    sensitive = true
 }
 
 //
-// Build lab resources in the new namespace.
+// Build lab resources in the new namespace. The system provider creates them
+// there through their namespace argument, so no provider is configured with
+// the student key, which is only known once it has been generated.
 //
-provider "shakenfist" {
-    server_url = "http://sf-1:13000"
-    namespace = shakenfist_namespace.lab123.name
-    key = shakenfist_key.key1.key
-}
 
 // Jump host
 resource "shakenfist_instance" "jump" {
     name = "jump"
+    namespace = shakenfist_namespace.lab123.name
     cpus = 1
     memory = 1024
     disk {
@@ -75,11 +79,13 @@ resource "shakenfist_instance" "jump" {
 }
 
 resource "shakenfist_float" "external" {
+    namespace = shakenfist_namespace.lab123.name
     interface = shakenfist_instance.jump.network[0].interface_uuid
 }
 
 resource "shakenfist_network" "external" {
     name = "external"
+    namespace = shakenfist_namespace.lab123.name
     netblock = "10.0.1.0/24"
     provide_dhcp = true
     provide_nat = true
@@ -91,6 +97,7 @@ resource "shakenfist_network" "external" {
 // Target host
 resource "shakenfist_instance" "target" {
     name = "target"
+    namespace = shakenfist_namespace.lab123.name
     cpus = 1
     memory = 1024
     disk {
@@ -110,6 +117,7 @@ resource "shakenfist_instance" "target" {
 
 resource "shakenfist_network" "internal" {
     name = "internal"
+    namespace = shakenfist_namespace.lab123.name
     netblock = "10.0.2.0/24"
     provide_dhcp = true
     provide_nat = false
@@ -3,7 +3,8 @@ module github.com/shakenfist/terraform-provider-shakenfist
 go 1.14
 
 require (
-	github.com/hashicorp/terraform-plugin-sdk v1.7.0
+	github.com/hashicorp/go-cty v1.4.1-0.20200414143053-d3edf31b6320
+	github.com/hashicorp/terraform-plugin-sdk/v2 v2.7.0
 	github.com/shakenfist/client-go v0.4.19
 	golang.org/x/crypto v0.0.0-20191011191535-87dc89f01550 // indirect
 )
@@ -0,0 +1,142 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// clientConfig is the Shaken Fist client configuration file, as used by the
+// Shaken Fist command line client. Named profiles can be added to the file,
+// each profile has the same keys as the top level of the file.
+type clientConfig struct {
+	APIURL    string                  `json:"apiurl"`
+	Namespace string                  `json:"namespace"`
+	Key       string                  `json:"key"`
+	Profiles  map[string]clientConfig `json:"profiles"`
+}
+
+// defaultConfigFiles returns the locations searched for the client
+// configuration file when one is not specified, in order of preference.
+func defaultConfigFiles() []string {
+	var paths []string
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".shakenfist"))
+	}
+	return append(paths, "/etc/sf/shakenfist.json")
+}
+
+// loadClientConfig reads the client configuration file and returns the
+// settings of the profile. An empty profile returns the top level settings.
+//
+// If path is empty, the default locations are searched and a missing file is
+// not an error.
+func loadClientConfig(path, profile string) (clientConfig, error) {
+	var data []byte
+	var err error
+
+	if path != "" {
+		data, err = ioutil.ReadFile(path)
+		if err != nil {
+			return clientConfig{}, fmt.Errorf(
+				"Unable to read config file: %v", err)
+		}
+	} else {
+		for _, p := range defaultConfigFiles() {
+			data, err = ioutil.ReadFile(p)
+			if err == nil {
+				path = p
+				break
+			}
+			if !os.IsNotExist(err) {
+				return clientConfig{}, fmt.Errorf(
+					"Unable to read config file: %v", err)
+			}
+		}
+	}
+
+	if path == "" {
+		if profile != "" {
+			return clientConfig{}, fmt.Errorf(
+				"Profile %s requested but no config file found", profile)
+		}
+		return clientConfig{}, nil
+	}
+
+	var conf clientConfig
+	if err := json.Unmarshal(data, &conf); err != nil {
+		return clientConfig{}, fmt.Errorf(
+			"Unable to parse config file %s: %v", path, err)
+	}
+
+	if profile == "" {
+		return conf, nil
+	}
+
+	p, ok := conf.Profiles[profile]
+	if !ok {
+		return clientConfig{}, fmt.Errorf(
+			"Profile %s not found in config file %s", profile, path)
+	}
+	return p, nil
+}
+
+// resolveCredentials returns the server URL, namespace and key of the
+// provider, from those set by arguments or environment variables and the
+// client config file at path.
+//
+// A selected profile provides all three as a set, so that a key is never sent
+// to a server or namespace it does not belong to. Arguments and environment
+// variables setting a different value are rejected with the profile. Without
+// a profile, unset values are taken from the top level of the config file,
+// the key only if it is for the same namespace.
+func resolveCredentials(set clientConfig, path, profile string) (clientConfig,
+	error) {
+
+	if profile != "" {
+		conf, err := loadClientConfig(path, profile)
+		if err != nil {
+			return clientConfig{}, err
+		}
+		if conf.APIURL == "" || conf.Namespace == "" || conf.Key == "" {
+			return clientConfig{}, fmt.Errorf(
+				"Profile %s must set apiurl, namespace and key", profile)
+		}
+
+		if (set.APIURL != "" && set.APIURL != conf.APIURL) ||
+			(set.Namespace != "" && set.Namespace != conf.Namespace) ||
+			(set.Key != "" && set.Key != conf.Key) {
+			return clientConfig{}, fmt.Errorf("Profile %s cannot be used "+
+				"with a different server URL, namespace or key set by "+
+				"provider arguments or environment variables", profile)
+		}
+
+		return clientConfig{
+			APIURL:    conf.APIURL,
+			Namespace: conf.Namespace,
+			Key:       conf.Key,
+		}, nil
+	}
+
+	if set.APIURL != "" && set.Namespace != "" && set.Key != "" {
+		return set, nil
+	}
+
+	conf, err := loadClientConfig(path, "")
+	if err != nil {
+		return clientConfig{}, err
+	}
+
+	if set.APIURL == "" {
+		set.APIURL = conf.APIURL
+	}
+	if set.Namespace == "" {
+		set.Namespace = conf.Namespace
+	}
+	if set.Key == "" && set.Namespace == conf.Namespace {
+		set.Key = conf.Key
+	}
+	return set, nil
+}
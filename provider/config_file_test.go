@@ -0,0 +1,139 @@
+package provider
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnitLoadClientConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sfconfig")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "shakenfist.json")
+	err = ioutil.WriteFile(path, []byte(`{
+		"apiurl": "http://sf-1:13000",
+		"namespace": "devtest",
+		"key": "longsecurekey",
+		"profiles": {
+			"lab": {
+				"apiurl": "http://sf-lab:13000",
+				"namespace": "lab123",
+				"key": "labkey"
+			}
+		}
+	}`), 0600)
+	if err != nil {
+		t.Fatalf("Unable to write config file: %v", err)
+	}
+
+	conf, err := loadClientConfig(path, "")
+	if err != nil {
+		t.Fatalf("Unable to load config file: %v", err)
+	}
+	if conf.APIURL != "http://sf-1:13000" || conf.Namespace != "devtest" ||
+		conf.Key != "longsecurekey" {
+		t.Errorf("Incorrect default settings: %+v", conf)
+	}
+
+	conf, err = loadClientConfig(path, "lab")
+	if err != nil {
+		t.Fatalf("Unable to load config profile: %v", err)
+	}
+	if conf.APIURL != "http://sf-lab:13000" || conf.Namespace != "lab123" ||
+		conf.Key != "labkey" {
+		t.Errorf("Incorrect profile settings: %+v", conf)
+	}
+
+	if _, err := loadClientConfig(path, "missing"); err == nil {
+		t.Errorf("Missing profile should fail")
+	}
+
+	if _, err := loadClientConfig(filepath.Join(dir, "none"), ""); err == nil {
+		t.Errorf("Missing config file should fail")
+	}
+}
+
+func TestUnitResolveCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sfconfig")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "shakenfist.json")
+	err = ioutil.WriteFile(path, []byte(`{
+		"apiurl": "http://sf-1:13000",
+		"namespace": "devtest",
+		"key": "longsecurekey",
+		"profiles": {
+			"lab": {
+				"apiurl": "http://sf-lab:13000",
+				"namespace": "lab123",
+				"key": "labkey"
+			},
+			"partial": {
+				"namespace": "lab456"
+			}
+		}
+	}`), 0600)
+	if err != nil {
+		t.Fatalf("Unable to write config file: %v", err)
+	}
+
+	// A profile is used as a set
+	creds, err := resolveCredentials(clientConfig{}, path, "lab")
+	if err != nil {
+		t.Fatalf("Unable to resolve profile credentials: %v", err)
+	}
+	if creds.APIURL != "http://sf-lab:13000" || creds.Namespace != "lab123" ||
+		creds.Key != "labkey" {
+		t.Errorf("Incorrect profile credentials: %+v", creds)
+	}
+
+	// Values matching the profile may also be set
+	if _, err := resolveCredentials(clientConfig{
+		Namespace: "lab123",
+	}, path, "lab"); err != nil {
+		t.Errorf("Profile namespace set again was rejected: %v", err)
+	}
+
+	// A different server URL in the environment is rejected with a profile
+	if _, err := resolveCredentials(clientConfig{
+		APIURL: "http://sf-other:13000",
+	}, path, "lab"); err == nil {
+		t.Errorf("Profile with a different server URL was accepted")
+	}
+
+	if _, err := resolveCredentials(clientConfig{}, path,
+		"partial"); err == nil {
+		t.Errorf("Profile without a server URL and key was accepted")
+	}
+
+	// Without a profile, unset values come from the config file
+	creds, err = resolveCredentials(clientConfig{
+		Key: "otherkey",
+	}, path, "")
+	if err != nil {
+		t.Fatalf("Unable to resolve credentials: %v", err)
+	}
+	if creds.APIURL != "http://sf-1:13000" || creds.Namespace != "devtest" ||
+		creds.Key != "otherkey" {
+		t.Errorf("Incorrect credentials: %+v", creds)
+	}
+
+	// The config file key is not used for another namespace
+	creds, err = resolveCredentials(clientConfig{
+		Namespace: "lab123",
+	}, path, "")
+	if err != nil {
+		t.Fatalf("Unable to resolve credentials: %v", err)
+	}
+	if creds.Key != "" {
+		t.Errorf("Key of namespace devtest used for namespace lab123")
+	}
+}
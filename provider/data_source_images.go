@@ -0,0 +1,97 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceImages() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"node": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only list images cached on this node",
+			},
+			"url": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only list images with this URL",
+			},
+			"images": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The matching cached images",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"url": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "URL of the image",
+						},
+						"node": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Node the image is cached on",
+						},
+						"checksum": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Checksum of the cached image",
+						},
+						"size": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Size of the cached image in bytes",
+						},
+						"state": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "State of the cached image",
+						},
+					},
+				},
+			},
+		},
+		ReadContext: dataSourceReadImages,
+	}
+}
+
+func dataSourceReadImages(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
+
+	images, err := apiClient.GetImages(d.Get("node").(string))
+	if err != nil {
+		return diag.Errorf("Unable to retrieve images: %v", err)
+	}
+
+	url := d.Get("url").(string)
+
+	var ids []string
+	var found []map[string]interface{}
+	for _, img := range images {
+		if url != "" && img.URL != url {
+			continue
+		}
+
+		ids = append(ids, img.Node+"/"+img.URL)
+		found = append(found, map[string]interface{}{
+			"url":      img.URL,
+			"node":     img.Node,
+			"checksum": img.Checksum,
+			"size":     int(img.Size),
+			"state":    img.State,
+		})
+	}
+
+	if err := d.Set("images", found); err != nil {
+		return diag.Errorf("Images cannot be set: %v", err)
+	}
+	d.SetId(listDataSourceID(ids))
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	client "github.com/shakenfist/client-go"
+)
+
+func dataSourceInstance() *schema.Resource {
+	s := instanceDataSchema()
+
+	s["uuid"].Optional = true
+	s["uuid"].ExactlyOneOf = []string{"uuid", "name"}
+	s["name"].Optional = true
+	s["name"].ExactlyOneOf = []string{"uuid", "name"}
+
+	return &schema.Resource{
+		Schema:      s,
+		ReadContext: dataSourceReadInstance,
+	}
+}
+
+// instanceDataSchema returns the computed attributes of an instance as
+// exposed by the instance data sources.
+func instanceDataSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"uuid": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The UUID of the instance",
+		},
+		"name": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The name of the instance",
+		},
+		"cpus": {
+			Type:        schema.TypeInt,
+			Computed:    true,
+			Description: "The number of CPUs for the instance",
+		},
+		"memory": {
+			Type:        schema.TypeInt,
+			Computed:    true,
+			Description: "The amount of RAM for the instance in MB",
+		},
+		"disk": {
+			Type:     schema.TypeList,
+			Computed: true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"size": {
+						Type:        schema.TypeInt,
+						Computed:    true,
+						Description: "Size of disk in GB",
+					},
+					"base": {
+						Type:        schema.TypeString,
+						Computed:    true,
+						Description: "URL of disk image (or shortcut)",
+					},
+					"bus": {
+						Type:        schema.TypeString,
+						Computed:    true,
+						Description: "Bus type of disk",
+					},
+					"type": {
+						Type:        schema.TypeString,
+						Computed:    true,
+						Description: "Type of disk",
+					},
+				},
+			},
+		},
+		"video": {
+			Type:     schema.TypeList,
+			Computed: true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"memory": {
+						Type:        schema.TypeInt,
+						Computed:    true,
+						Description: "The amount of video card RAM in KB",
+					},
+					"model": {
+						Type:        schema.TypeString,
+						Computed:    true,
+						Description: "The video card model",
+					},
+				},
+			},
+		},
+		"network": {
+			Type:     schema.TypeList,
+			Computed: true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"network_uuid": {
+						Type:        schema.TypeString,
+						Computed:    true,
+						Description: "The UUID of the network",
+					},
+					"ipv4": {
+						Type:     schema.TypeString,
+						Computed: true,
+						Description: "The " +
+							"IPv4 address of the network interface",
+					},
+					"mac": {
+						Type:     schema.TypeString,
+						Computed: true,
+						Description: "The " +
+							"MAC address of the network interface",
+					},
+					"model": {
+						Type:        schema.TypeString,
+						Computed:    true,
+						Description: "The model of the network interface",
+					},
+					"interface_uuid": {
+						Type:        schema.TypeString,
+						Computed:    true,
+						Description: "The UUID of the network interface",
+					},
+					"state": {
+						Type:        schema.TypeString,
+						Computed:    true,
+						Description: "The state of the network interface",
+					},
+				},
+			},
+		},
+		"ssh_key": {
+			Type:     schema.TypeString,
+			Computed: true,
+			Description: "The " +
+				"ssh key embedded into the instance via config drive",
+		},
+		"node": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Shaken Fist node running this instance",
+		},
+		"user_data": {
+			Type:     schema.TypeString,
+			Computed: true,
+			Description: "User data passed " +
+				"to the instance via config drive, encoded as base64",
+		},
+		"metadata": {
+			Type:     schema.TypeMap,
+			Computed: true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString},
+		},
+		"console_port": {
+			Type:        schema.TypeInt,
+			Computed:    true,
+			Description: "Console port number",
+		},
+		"vdi_port": {
+			Type:        schema.TypeInt,
+			Computed:    true,
+			Description: "VDI port number",
+		},
+		"state": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "State of the instance",
+		},
+		"power_state": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Power state of the instance",
+		},
+	}
+}
+
+func dataSourceReadInstance(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
+
+	var inst client.Instance
+	var err error
+
+	if uuid, ok := d.GetOk("uuid"); ok {
+		inst, err = apiClient.GetInstance(uuid.(string))
+		if err != nil {
+			return diag.Errorf("Unable to retrieve instance: %v", err)
+		}
+	} else {
+		inst, err = findInstanceByName(apiClient, d.Get("name").(string))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	d.SetId(inst.UUID)
+
+	return diag.FromErr(setInstanceData(d, apiClient, inst))
+}
+
+// findInstanceByName returns the single instance in the namespace with the
+// given name. Deleted instances are ignored. It is an error for the name to
+// match no instance or more than one instance.
+func findInstanceByName(apiClient *client.Client,
+	name string) (client.Instance, error) {
+
+	instances, err := apiClient.GetInstances()
+	if err != nil {
+		return client.Instance{}, fmt.Errorf(
+			"Unable to retrieve instances: %v", err)
+	}
+
+	var found []client.Instance
+	for _, i := range instances {
+		if i.Name == name && i.State != "deleted" {
+			found = append(found, i)
+		}
+	}
+
+	if len(found) == 0 {
+		return client.Instance{}, fmt.Errorf(
+			"No instance found with name %s", name)
+	}
+	if len(found) > 1 {
+		return client.Instance{}, fmt.Errorf(
+			"%d instances found with name %s, use the uuid instead",
+			len(found), name)
+	}
+
+	return found[0], nil
+}
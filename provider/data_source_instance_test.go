@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccShakenFistDataInstance(t *testing.T) {
+	randomName := acctest.RandStringFromCharSet(10, acctest.CharSetAlphaNum)
+
+	resInst := "shakenfist_instance.jump"
+	dataByName := "data.shakenfist_instance.by_name"
+	dataByUUID := "data.shakenfist_instance.by_uuid"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataInstance(randomName),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrPair(
+						dataByName, "uuid", resInst, "uuid"),
+					resource.TestCheckResourceAttrPair(
+						dataByName, "node", resInst, "node"),
+					resource.TestCheckResourceAttrPair(
+						dataByName, "network.0.interface_uuid",
+						resInst, "network.0.interface_uuid"),
+					resource.TestCheckResourceAttr(
+						dataByName, "metadata.person", "old man"),
+
+					resource.TestCheckResourceAttr(dataByUUID, "name",
+						"testacc-"+randomName+"-jump"),
+					resource.TestCheckResourceAttrPair(
+						dataByUUID, "network.0.ipv4",
+						resInst, "network.0.ipv4"),
+					resource.TestCheckResourceAttrSet(dataByUUID, "state"),
+					resource.TestCheckResourceAttrSet(
+						dataByUUID, "power_state"),
+				),
+			},
+		},
+	})
+}
+
+func testAccDataInstance(randomName string) string {
+	res := `
+	resource "shakenfist_instance" "jump" {
+		name = "testacc-{name}-jump"
+		cpus = 1
+		memory = 1024
+		disk {
+			size = 8
+			base = "cirros"
+			bus = "ide"
+			type = "disk"
+		}
+		network {
+			network_uuid = shakenfist_network.external.id
+		}
+		metadata = {
+			person = "old man"
+		}
+	}
+
+	resource "shakenfist_network" "external" {
+		name = "testacc-{name}-external"
+		netblock = "10.0.1.0/24"
+		provide_dhcp = true
+		provide_nat = false
+	}
+
+	data "shakenfist_instance" "by_name" {
+		name = shakenfist_instance.jump.name
+	}
+
+	data "shakenfist_instance" "by_uuid" {
+		uuid = shakenfist_instance.jump.uuid
+	}`
+
+	r := strings.NewReplacer("{name}", randomName)
+	return r.Replace(res)
+}
@@ -0,0 +1,127 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	client "github.com/shakenfist/client-go"
+)
+
+func dataSourceInstances() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"name_prefix": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only list instances with names starting with this",
+			},
+			"state": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Description: "Only list instances in this state, " +
+					"by default all instances that are not deleted",
+			},
+			"match_metadata": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				Description: "Metadata keys and values that " +
+					"the instances must have",
+				Elem: &schema.Schema{
+					Type: schema.TypeString},
+			},
+			"instances": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The matching instances",
+				Elem: &schema.Resource{
+					Schema: instanceDataSchema(),
+				},
+			},
+		},
+		ReadContext: dataSourceReadInstances,
+	}
+}
+
+func dataSourceReadInstances(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
+
+	instances, err := apiClient.GetInstances()
+	if err != nil {
+		return diag.Errorf("Unable to retrieve instances: %v", err)
+	}
+
+	namePrefix := d.Get("name_prefix").(string)
+	state := d.Get("state").(string)
+	matchMeta := d.Get("match_metadata").(map[string]interface{})
+
+	var uuids []string
+	var found []map[string]interface{}
+	for _, inst := range instances {
+		if !filterNamePrefix(inst.Name, namePrefix) ||
+			!filterState(inst.State, state) {
+			continue
+		}
+
+		match, err := matchMetadata(client.TypeInstance, inst.UUID, matchMeta,
+			apiClient)
+		if err != nil {
+			return diag.Errorf("ReadInstances error: %v", err)
+		}
+		if !match {
+			continue
+		}
+
+		i, err := flattenInstance(apiClient, inst)
+		if err != nil {
+			return diag.Errorf("ReadInstances error: %v", err)
+		}
+
+		uuids = append(uuids, inst.UUID)
+		found = append(found, i)
+	}
+
+	if err := d.Set("instances", found); err != nil {
+		return diag.Errorf("Instances cannot be set: %v", err)
+	}
+	d.SetId(listDataSourceID(uuids))
+
+	return nil
+}
+
+// flattenInstance converts the Shaken Fist instance to the attributes
+// described by instanceDataSchema.
+func flattenInstance(apiClient *client.Client,
+	inst client.Instance) (map[string]interface{}, error) {
+
+	networks, err := flattenInstanceNetworks(apiClient, inst.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := apiClient.GetMetadata(client.TypeInstance, inst.UUID)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve metadata: %v", err)
+	}
+
+	return map[string]interface{}{
+		"uuid":         inst.UUID,
+		"name":         inst.Name,
+		"cpus":         inst.CPUs,
+		"memory":       inst.Memory,
+		"disk":         flattenDiskSpecs(inst.DiskSpecs),
+		"video":        flattenVideoSpec(inst.Video),
+		"network":      networks,
+		"ssh_key":      inst.SSHKey,
+		"node":         inst.Node,
+		"user_data":    inst.UserData,
+		"metadata":     metadata,
+		"console_port": inst.ConsolePort,
+		"vdi_port":     inst.VDIPort,
+		"state":        inst.State,
+		"power_state":  inst.PowerState,
+	}, nil
+}
@@ -0,0 +1,108 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccShakenFistDataInstances(t *testing.T) {
+	randomName := acctest.RandStringFromCharSet(10, acctest.CharSetAlphaNum)
+
+	dataAll := "data.shakenfist_instances.all"
+	dataWorkers := "data.shakenfist_instances.workers"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataInstances(randomName),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						dataAll, "instances.#", "2"),
+
+					resource.TestCheckResourceAttr(
+						dataWorkers, "instances.#", "1"),
+					resource.TestCheckResourceAttrPair(
+						dataWorkers, "instances.0.uuid",
+						"shakenfist_instance.worker", "uuid"),
+					resource.TestCheckResourceAttr(
+						dataWorkers, "instances.0.metadata.role", "worker"),
+					resource.TestCheckResourceAttrSet(
+						dataWorkers, "instances.0.network.0.interface_uuid"),
+				),
+			},
+		},
+	})
+}
+
+func testAccDataInstances(randomName string) string {
+	res := `
+	resource "shakenfist_instance" "worker" {
+		name = "testacc-{name}-worker"
+		cpus = 1
+		memory = 1024
+		disk {
+			size = 8
+			base = "cirros"
+			bus = "ide"
+			type = "disk"
+		}
+		network {
+			network_uuid = shakenfist_network.external.id
+		}
+		metadata = {
+			role = "worker"
+		}
+	}
+
+	resource "shakenfist_instance" "jump" {
+		name = "testacc-{name}-jump"
+		cpus = 1
+		memory = 1024
+		disk {
+			size = 8
+			base = "cirros"
+			bus = "ide"
+			type = "disk"
+		}
+		network {
+			network_uuid = shakenfist_network.external.id
+		}
+		metadata = {
+			role = "jump"
+		}
+	}
+
+	resource "shakenfist_network" "external" {
+		name = "testacc-{name}-external"
+		netblock = "10.0.1.0/24"
+		provide_dhcp = true
+		provide_nat = false
+	}
+
+	data "shakenfist_instances" "all" {
+		name_prefix = "testacc-{name}-"
+		depends_on = [
+			shakenfist_instance.worker,
+			shakenfist_instance.jump,
+		]
+	}
+
+	data "shakenfist_instances" "workers" {
+		name_prefix = "testacc-{name}-"
+		match_metadata = {
+			role = "worker"
+		}
+		depends_on = [
+			shakenfist_instance.worker,
+			shakenfist_instance.jump,
+		]
+	}`
+
+	r := strings.NewReplacer("{name}", randomName)
+	return r.Replace(res)
+}
@@ -0,0 +1,150 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	client "github.com/shakenfist/client-go"
+)
+
+func dataSourceNetwork() *schema.Resource {
+	s := networkDataSchema()
+
+	s["uuid"].Optional = true
+	s["uuid"].ConflictsWith = []string{"name", "match_metadata"}
+	s["uuid"].AtLeastOneOf = []string{"uuid", "name", "match_metadata"}
+	s["name"].Optional = true
+	s["name"].AtLeastOneOf = []string{"uuid", "name", "match_metadata"}
+
+	s["match_metadata"] = &schema.Schema{
+		Type:     schema.TypeMap,
+		Optional: true,
+		Description: "Metadata keys and values that " +
+			"the network must have",
+		Elem: &schema.Schema{
+			Type: schema.TypeString},
+		AtLeastOneOf: []string{"uuid", "name", "match_metadata"},
+	}
+
+	return &schema.Resource{
+		Schema:      s,
+		ReadContext: dataSourceReadNetwork,
+	}
+}
+
+// networkDataSchema returns the computed attributes of a network as exposed
+// by the network data sources.
+func networkDataSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"uuid": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The UUID of the network",
+		},
+		"name": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The name of the network",
+		},
+		"netblock": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The CIDR IP range of the network",
+		},
+		"provide_dhcp": {
+			Type:        schema.TypeBool,
+			Computed:    true,
+			Description: "Do DHCP services exist on the network?",
+		},
+		"provide_nat": {
+			Type:        schema.TypeBool,
+			Computed:    true,
+			Description: "Do NAT services exist on the network?",
+		},
+		"state": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "State of the network",
+		},
+		"metadata": {
+			Type:     schema.TypeMap,
+			Computed: true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString},
+		},
+	}
+}
+
+func dataSourceReadNetwork(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
+
+	var network client.Network
+	var err error
+
+	if uuid, ok := d.GetOk("uuid"); ok {
+		network, err = apiClient.GetNetwork(uuid.(string))
+		if err != nil {
+			return diag.Errorf("Unable to retrieve network: %v", err)
+		}
+	} else {
+		network, err = findNetwork(apiClient, d.Get("name").(string),
+			d.Get("match_metadata").(map[string]interface{}))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	d.SetId(network.UUID)
+
+	return diag.FromErr(setNetworkData(d, apiClient, network))
+}
+
+// findNetwork returns the single network in the namespace matching the name
+// (if not blank) and all of the metadata key/value pairs. Deleted networks
+// are ignored. It is an error for no network, or more than one network, to
+// match.
+func findNetwork(apiClient *client.Client, name string,
+	matchMeta map[string]interface{}) (client.Network, error) {
+
+	networks, err := apiClient.GetNetworks()
+	if err != nil {
+		return client.Network{}, fmt.Errorf(
+			"Unable to retrieve networks: %v", err)
+	}
+
+	var found []client.Network
+	for _, n := range networks {
+		if n.State == "deleted" {
+			continue
+		}
+		if name != "" && n.Name != name {
+			continue
+		}
+
+		match, err := matchMetadata(client.TypeNetwork, n.UUID, matchMeta,
+			apiClient)
+		if err != nil {
+			return client.Network{}, err
+		}
+		if match {
+			found = append(found, n)
+		}
+	}
+
+	if len(found) == 0 {
+		return client.Network{}, fmt.Errorf(
+			"No network found matching name %q and metadata %v",
+			name, matchMeta)
+	}
+	if len(found) > 1 {
+		return client.Network{}, fmt.Errorf(
+			"%d networks found matching name %q and metadata %v, "+
+				"use the uuid instead", len(found), name, matchMeta)
+	}
+
+	return found[0], nil
+}
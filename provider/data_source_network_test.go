@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccShakenFistDataNetwork(t *testing.T) {
+	randomName := acctest.RandStringFromCharSet(10, acctest.CharSetAlphaNum)
+
+	resNet := "shakenfist_network.external"
+	dataByName := "data.shakenfist_network.by_name"
+	dataByUUID := "data.shakenfist_network.by_uuid"
+	dataByMeta := "data.shakenfist_network.by_meta"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataNetwork(randomName),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrPair(
+						dataByName, "uuid", resNet, "uuid"),
+					resource.TestCheckResourceAttr(
+						dataByName, "netblock", "10.0.1.0/24"),
+					resource.TestCheckResourceAttr(
+						dataByName, "provide_dhcp", "true"),
+					resource.TestCheckResourceAttr(
+						dataByName, "provide_nat", "false"),
+					resource.TestCheckResourceAttr(
+						dataByName, "state", "created"),
+
+					resource.TestCheckResourceAttr(dataByUUID, "name",
+						"testacc-"+randomName+"-external"),
+					resource.TestCheckResourceAttr(
+						dataByUUID, "metadata.purpose", "external"),
+
+					resource.TestCheckResourceAttrPair(
+						dataByMeta, "uuid", resNet, "uuid"),
+				),
+			},
+		},
+	})
+}
+
+func testAccDataNetwork(randomName string) string {
+	res := `
+	resource "shakenfist_network" "external" {
+		name = "testacc-{name}-external"
+		netblock = "10.0.1.0/24"
+		provide_dhcp = true
+		provide_nat = false
+		metadata = {
+			purpose = "external"
+			testacc = "{name}"
+		}
+	}
+
+	data "shakenfist_network" "by_name" {
+		name = shakenfist_network.external.name
+	}
+
+	data "shakenfist_network" "by_uuid" {
+		uuid = shakenfist_network.external.uuid
+	}
+
+	data "shakenfist_network" "by_meta" {
+		match_metadata = {
+			testacc = shakenfist_network.external.metadata.testacc
+		}
+	}`
+
+	r := strings.NewReplacer("{name}", randomName)
+	return r.Replace(res)
+}
@@ -0,0 +1,114 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	client "github.com/shakenfist/client-go"
+)
+
+func dataSourceNetworks() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"name_prefix": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only list networks with names starting with this",
+			},
+			"state": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Description: "Only list networks in this state, " +
+					"by default all networks that are not deleted",
+			},
+			"match_metadata": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				Description: "Metadata keys and values that " +
+					"the networks must have",
+				Elem: &schema.Schema{
+					Type: schema.TypeString},
+			},
+			"networks": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The matching networks",
+				Elem: &schema.Resource{
+					Schema: networkDataSchema(),
+				},
+			},
+		},
+		ReadContext: dataSourceReadNetworks,
+	}
+}
+
+func dataSourceReadNetworks(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
+
+	networks, err := apiClient.GetNetworks()
+	if err != nil {
+		return diag.Errorf("Unable to retrieve networks: %v", err)
+	}
+
+	namePrefix := d.Get("name_prefix").(string)
+	state := d.Get("state").(string)
+	matchMeta := d.Get("match_metadata").(map[string]interface{})
+
+	var uuids []string
+	var found []map[string]interface{}
+	for _, network := range networks {
+		if !filterNamePrefix(network.Name, namePrefix) ||
+			!filterState(network.State, state) {
+			continue
+		}
+
+		match, err := matchMetadata(client.TypeNetwork, network.UUID,
+			matchMeta, apiClient)
+		if err != nil {
+			return diag.Errorf("ReadNetworks error: %v", err)
+		}
+		if !match {
+			continue
+		}
+
+		n, err := flattenNetwork(apiClient, network)
+		if err != nil {
+			return diag.Errorf("ReadNetworks error: %v", err)
+		}
+
+		uuids = append(uuids, network.UUID)
+		found = append(found, n)
+	}
+
+	if err := d.Set("networks", found); err != nil {
+		return diag.Errorf("Networks cannot be set: %v", err)
+	}
+	d.SetId(listDataSourceID(uuids))
+
+	return nil
+}
+
+// flattenNetwork converts the Shaken Fist network to the attributes
+// described by networkDataSchema.
+func flattenNetwork(apiClient *client.Client,
+	network client.Network) (map[string]interface{}, error) {
+
+	metadata, err := apiClient.GetNetworkMetadata(network.UUID)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve metadata: %v", err)
+	}
+
+	return map[string]interface{}{
+		"uuid":         network.UUID,
+		"name":         network.Name,
+		"netblock":     network.NetBlock,
+		"provide_dhcp": network.ProvideDHCP,
+		"provide_nat":  network.ProvideNAT,
+		"state":        network.State,
+		"metadata":     metadata,
+	}, nil
+}
@@ -0,0 +1,85 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccShakenFistDataNetworks(t *testing.T) {
+	randomName := acctest.RandStringFromCharSet(10, acctest.CharSetAlphaNum)
+
+	dataAll := "data.shakenfist_networks.all"
+	dataInternal := "data.shakenfist_networks.internal"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataNetworks(randomName),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						dataAll, "networks.#", "2"),
+
+					resource.TestCheckResourceAttr(
+						dataInternal, "networks.#", "1"),
+					resource.TestCheckResourceAttrPair(
+						dataInternal, "networks.0.uuid",
+						"shakenfist_network.internal", "uuid"),
+					resource.TestCheckResourceAttr(
+						dataInternal, "networks.0.netblock", "10.0.2.0/24"),
+					resource.TestCheckResourceAttr(
+						dataInternal, "networks.0.state", "created"),
+				),
+			},
+		},
+	})
+}
+
+func testAccDataNetworks(randomName string) string {
+	res := `
+	resource "shakenfist_network" "external" {
+		name = "testacc-{name}-external"
+		netblock = "10.0.1.0/24"
+		provide_dhcp = true
+		provide_nat = false
+		metadata = {
+			purpose = "external"
+		}
+	}
+
+	resource "shakenfist_network" "internal" {
+		name = "testacc-{name}-internal"
+		netblock = "10.0.2.0/24"
+		provide_dhcp = true
+		provide_nat = false
+		metadata = {
+			purpose = "internal"
+		}
+	}
+
+	data "shakenfist_networks" "all" {
+		name_prefix = "testacc-{name}-"
+		depends_on = [
+			shakenfist_network.external,
+			shakenfist_network.internal,
+		]
+	}
+
+	data "shakenfist_networks" "internal" {
+		name_prefix = "testacc-{name}-"
+		match_metadata = {
+			purpose = "internal"
+		}
+		depends_on = [
+			shakenfist_network.external,
+			shakenfist_network.internal,
+		]
+	}`
+
+	r := strings.NewReplacer("{name}", randomName)
+	return r.Replace(res)
+}
@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	client "github.com/shakenfist/client-go"
+)
+
+func dataSourceNodes() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"online_only": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Only list nodes that are online",
+			},
+			"online_threshold": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "5m",
+				Description: "Nodes last seen " +
+					"longer ago than this duration are offline",
+				ValidateDiagFunc: validateDuration,
+			},
+			"nodes": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The matching nodes",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The name of the node",
+						},
+						"ip": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The IP address of the node",
+						},
+						"last_seen": {
+							Type:     schema.TypeString,
+							Computed: true,
+							Description: "When the node last " +
+								"checked in, in RFC 3339 format",
+						},
+						"version": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The Shaken Fist version of the node",
+						},
+						"online": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Whether the node is online",
+						},
+					},
+				},
+			},
+		},
+		ReadContext: dataSourceReadNodes,
+	}
+}
+
+func dataSourceReadNodes(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
+
+	nodes, err := apiClient.GetNodes()
+	if err != nil {
+		return diag.Errorf("Unable to retrieve nodes: %v", err)
+	}
+
+	threshold, err := time.ParseDuration(d.Get("online_threshold").(string))
+	if err != nil {
+		return diag.Errorf("Invalid online_threshold: %v", err)
+	}
+	onlineOnly := d.Get("online_only").(bool)
+
+	var names []string
+	var found []map[string]interface{}
+	for _, node := range nodes {
+		n := flattenNode(node, threshold, time.Now())
+		if onlineOnly && !n["online"].(bool) {
+			continue
+		}
+
+		names = append(names, node.Name)
+		found = append(found, n)
+	}
+
+	if err := d.Set("nodes", found); err != nil {
+		return diag.Errorf("Nodes cannot be set: %v", err)
+	}
+	d.SetId(listDataSourceID(names))
+
+	return nil
+}
+
+// flattenNode converts the Shaken Fist node to Terraform attributes. The node
+// is online if it was last seen within the threshold before now.
+func flattenNode(node client.Node, threshold time.Duration,
+	now time.Time) map[string]interface{} {
+
+	sec, frac := math.Modf(node.LastSeen)
+	lastSeen := time.Unix(int64(sec), int64(frac*1e9)).UTC()
+
+	return map[string]interface{}{
+		"name":      node.Name,
+		"ip":        node.IP,
+		"last_seen": lastSeen.Format(time.RFC3339),
+		"version":   node.Version,
+		"online":    node.LastSeen > 0 && now.Sub(lastSeen) <= threshold,
+	}
+}
@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	client "github.com/shakenfist/client-go"
+)
+
+func TestAccShakenFistDataNodes(t *testing.T) {
+	dataAll := "data.shakenfist_nodes.all"
+	dataOnline := "data.shakenfist_nodes.online"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataNodes(),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet(dataAll, "nodes.0.name"),
+					resource.TestCheckResourceAttrSet(dataAll, "nodes.0.ip"),
+					resource.TestCheckResourceAttrSet(
+						dataAll, "nodes.0.last_seen"),
+					resource.TestCheckResourceAttrSet(
+						dataAll, "nodes.0.version"),
+
+					resource.TestCheckResourceAttr(
+						dataOnline, "nodes.0.online", "true"),
+				),
+			},
+		},
+	})
+}
+
+func testAccDataNodes() string {
+	return `
+	data "shakenfist_nodes" "all" {
+	}
+
+	data "shakenfist_nodes" "online" {
+		online_only = true
+	}`
+}
+
+func TestUnitFlattenNode(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	node := client.Node{
+		Name:     "sf-1",
+		IP:       "192.168.1.1",
+		LastSeen: float64(now.Add(-time.Minute).Unix()),
+		Version:  "0.4.0",
+	}
+
+	n := flattenNode(node, 5*time.Minute, now)
+	if n["last_seen"] != "2021-06-01T11:59:00Z" {
+		t.Errorf("Incorrect last_seen: %v", n["last_seen"])
+	}
+	if n["online"] != true {
+		t.Errorf("Node seen a minute ago is offline")
+	}
+
+	n = flattenNode(node, 30*time.Second, now)
+	if n["online"] != false {
+		t.Errorf("Node seen a minute ago is online with 30s threshold")
+	}
+
+	node.LastSeen = 0
+	n = flattenNode(node, 5*time.Minute, now)
+	if n["online"] != false {
+		t.Errorf("Node never seen is online")
+	}
+}
@@ -0,0 +1,117 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+// errorKind is the class of failure returned by a Shaken Fist API call.
+type errorKind int
+
+const (
+	errorUnknown errorKind = iota
+	errorNotFound
+	errorUnauthorized
+	errorConflict
+	errorServer
+	errorTransport
+)
+
+func (k errorKind) String() string {
+	switch k {
+	case errorNotFound:
+		return "not found"
+	case errorUnauthorized:
+		return "unauthorized"
+	case errorConflict:
+		return "conflict"
+	case errorServer:
+		return "server error"
+	case errorTransport:
+		return "transport error"
+	}
+	return "unknown error"
+}
+
+// apiError is a failed Shaken Fist API response, classified so that callers
+// can decide whether to retry, fail or forget the resource. It is returned by
+// statusTransport and reaches the provider wrapped by the client library.
+type apiError struct {
+	Kind   errorKind
+	Status int
+	Err    error
+}
+
+func (e *apiError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *apiError) Unwrap() error {
+	return e.Err
+}
+
+// classifyError wraps err as an *apiError. The class comes from the HTTP
+// status recorded by statusTransport, or is a transport error if no response
+// was received. Errors are never classified from their text, so any other
+// error is unknown. A nil error returns nil.
+func classifyError(err error) *apiError {
+	if err == nil {
+		return nil
+	}
+
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var urlErr *url.Error
+	var netErr net.Error
+	if errors.As(err, &urlErr) || errors.As(err, &netErr) {
+		return &apiError{Kind: errorTransport, Err: err}
+	}
+
+	return &apiError{Kind: errorUnknown, Err: err}
+}
+
+// statusKind returns the error class of an HTTP status code.
+func statusKind(status int) errorKind {
+	switch {
+	case status == 404:
+		return errorNotFound
+	case status == 401 || status == 403:
+		return errorUnauthorized
+	case status == 409:
+		return errorConflict
+	case status >= 500:
+		return errorServer
+	}
+	return errorUnknown
+}
+
+// isNotFound reports whether the API reported that the object does not exist.
+func isNotFound(err error) bool {
+	e := classifyError(err)
+	return e != nil && e.Kind == errorNotFound
+}
+
+// isRetryable reports whether the failure is likely to be transient.
+func isRetryable(err error) bool {
+	e := classifyError(err)
+	return e != nil && (e.Kind == errorServer || e.Kind == errorTransport)
+}
+
+// retryError converts an API error within a resource.Retry function to a
+// retryable error if the failure is transient, otherwise the retry stops.
+func retryError(err error, format string, a ...interface{}) *resource.RetryError {
+	retry := isRetryable(err)
+
+	err = fmt.Errorf(format+": %v", append(a, err)...)
+	if retry {
+		return resource.RetryableError(err)
+	}
+	return resource.NonRetryableError(err)
+}
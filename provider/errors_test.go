@@ -0,0 +1,130 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	client "github.com/shakenfist/client-go"
+)
+
+func TestUnitClassifyError(t *testing.T) {
+	notFound := &apiError{
+		Kind:   errorNotFound,
+		Status: 404,
+		Err:    errors.New("GET /instances/abc returned status 404"),
+	}
+
+	tests := []struct {
+		err    error
+		kind   errorKind
+		status int
+	}{
+		{
+			err:    notFound,
+			kind:   errorNotFound,
+			status: 404,
+		},
+		{
+			// As returned by http.Client for statusTransport errors
+			err: &url.Error{
+				Op:  "Get",
+				URL: "http://sf-1:13000/instances/abc",
+				Err: notFound,
+			},
+			kind:   errorNotFound,
+			status: 404,
+		},
+		{
+			err: &url.Error{
+				Op:  "Get",
+				URL: "http://sf-1:13000/instances",
+				Err: errors.New("connection refused"),
+			},
+			kind: errorTransport,
+		},
+		{
+			// Only the response status can mark an object as not found
+			err:  errors.New("network not found"),
+			kind: errorUnknown,
+		},
+		{
+			err:  errors.New(`status 404: {"error": "instance not found"}`),
+			kind: errorUnknown,
+		},
+		{
+			err:  errors.New("unexpected EOF"),
+			kind: errorUnknown,
+		},
+	}
+
+	for _, test := range tests {
+		e := classifyError(fmt.Errorf("wrapped: %w", test.err))
+		if e.Kind != test.kind {
+			t.Errorf("%q classified as %v, expected %v",
+				test.err, e.Kind, test.kind)
+		}
+		if e.Status != test.status {
+			t.Errorf("%q has status %d, expected %d",
+				test.err, e.Status, test.status)
+		}
+	}
+
+	if classifyError(nil) != nil {
+		t.Errorf("nil error should not be classified")
+	}
+}
+
+func TestUnitRetryError(t *testing.T) {
+	if !isRetryable(&apiError{Kind: statusKind(502), Status: 502,
+		Err: errors.New("status 502")}) {
+		t.Errorf("502 should be retryable")
+	}
+	if isRetryable(&apiError{Kind: statusKind(404), Status: 404,
+		Err: errors.New("status 404")}) {
+		t.Errorf("404 should not be retryable")
+	}
+	if isRetryable(errors.New("status 500")) {
+		t.Errorf("Unclassified errors should not be retryable")
+	}
+
+	r := retryError(&apiError{Kind: errorServer, Status: 500,
+		Err: errors.New("status 500")}, "Unable to get %s", "thing")
+	if !r.Retryable {
+		t.Errorf("retryError of 500 should be retryable")
+	}
+	if r.Err.Error() != "Unable to get thing: status 500" {
+		t.Errorf("retryError message is %q", r.Err.Error())
+	}
+}
+
+// TestUnitClientErrors checks the errors classified by statusTransport are
+// still recognised once returned through the Shaken Fist client library.
+func TestUnitClientErrors(t *testing.T) {
+	s := newMockServer("secret")
+	defer s.Close()
+
+	registerTestTransport(t, s.URL, apiSettings{})
+	defer unregisterTestTransport(s.URL)
+
+	apiClient := client.NewClient(s.URL, "system", "secret")
+
+	_, err := apiClient.GetInstance("a3a5c7e6-0000-4000-8000-000000000000")
+	if !isNotFound(err) {
+		t.Errorf("Missing instance is not reported as not found: %v", err)
+	}
+	if isRetryable(err) {
+		t.Errorf("Missing instance is reported as retryable: %v", err)
+	}
+
+	s.failRequests("GET", "/networks", http.StatusServiceUnavailable, -1)
+	_, err = apiClient.GetNetworks()
+	if !isRetryable(err) {
+		t.Errorf("Unavailable server is not reported as retryable: %v", err)
+	}
+	if isNotFound(err) {
+		t.Errorf("Unavailable server is reported as not found: %v", err)
+	}
+}
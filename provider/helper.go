@@ -1,9 +1,16 @@
 package provider
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
-	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	client "github.com/shakenfist/client-go"
 )
 
@@ -50,3 +57,97 @@ func updateMetadata(
 
 	return nil
 }
+
+// matchMetadata reports whether the Shaken Fist object has every key in
+// matchMeta set to the same value. An empty matchMeta always matches.
+func matchMetadata(
+	resType client.ResourceType,
+	uuid string,
+	matchMeta map[string]interface{},
+	apiClient *client.Client) (bool, error) {
+
+	if len(matchMeta) == 0 {
+		return true, nil
+	}
+
+	metadata, err := apiClient.GetMetadata(resType, uuid)
+	if err != nil {
+		return false, fmt.Errorf("Unable to retrieve metadata: %v", err)
+	}
+
+	for key, val := range matchMeta {
+		if actual, ok := metadata[key]; !ok || actual != val.(string) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// listDataSourceID returns a stable ID for a data source returning a list of
+// Shaken Fist objects, derived from the object UUIDs.
+func listDataSourceID(uuids []string) string {
+	sorted := append([]string{}, uuids...)
+	sort.Strings(sorted)
+
+	sum := sha1.Sum([]byte(strings.Join(sorted, ",")))
+	return hex.EncodeToString(sum[:])
+}
+
+// filterNamePrefix reports whether name begins with prefix, where an empty
+// prefix matches everything.
+func filterNamePrefix(name, prefix string) bool {
+	return prefix == "" || strings.HasPrefix(name, prefix)
+}
+
+// filterState reports whether the object state matches the requested state.
+// If no state is requested, every state except deleted matches.
+func filterState(state, wanted string) bool {
+	if wanted == "" {
+		return state != "deleted"
+	}
+	return state == wanted
+}
+
+// validateDuration checks the value is a Go duration string such as "30s".
+func validateDuration(v interface{}, path cty.Path) diag.Diagnostics {
+	value, ok := v.(string)
+	if !ok {
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "Expected duration to be a string",
+			AttributePath: path,
+		}}
+	}
+
+	if _, err := time.ParseDuration(value); err != nil {
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "Invalid duration, expected a value such as 30s",
+			Detail:        err.Error(),
+			AttributePath: path,
+		}}
+	}
+
+	return nil
+}
+
+// expandStringSet returns the sorted strings in the set.
+func expandStringSet(set *schema.Set) []string {
+	var values []string
+	for _, v := range set.List() {
+		values = append(values, v.(string))
+	}
+	sort.Strings(values)
+	return values
+}
+
+// containsString reports whether the value is in the list.
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
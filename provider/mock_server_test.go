@@ -0,0 +1,1175 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockServer is an in-process fake of the Shaken Fist REST API. It holds
+// nodes, namespaces, keys, networks, instances, interfaces, floating IPs,
+// cached images, snapshots and metadata in memory, so the acceptance tests
+// can be run without a cluster.
+//
+// Objects are created in the initial state and become created after being read
+// a few times, and deleted objects are deleting until read again, as with a
+// real cluster. Errors can be injected with failRequests.
+type mockServer struct {
+	*httptest.Server
+
+	lock       sync.Mutex
+	nodes      []string
+	namespaces map[string]*mockNamespace
+	tokens     map[string]string
+	networks   map[string]*mockNetwork
+	instances  map[string]*mockInstance
+	interfaces map[string]*mockInterface
+	images     map[string]*mockImage
+	snapshots  map[string]*mockSnapshot
+	failures   []*mockFailure
+	nextFloat  int
+}
+
+// mockNamespace is a namespace and its keys.
+type mockNamespace struct {
+	keys     map[string]string
+	metadata map[string]string
+}
+
+// mockObject holds the lifecycle shared by networks, instances and interfaces.
+type mockObject struct {
+	State     string `json:"state"`
+	Namespace string `json:"namespace"`
+
+	// pending are the states the object moves through on subsequent reads
+	pending  []string
+	metadata map[string]string
+}
+
+// advance moves the object to its next pending state.
+func (o *mockObject) advance() {
+	if len(o.pending) > 0 {
+		o.State = o.pending[0]
+		o.pending = o.pending[1:]
+	}
+}
+
+type mockNetwork struct {
+	mockObject
+	UUID        string `json:"uuid"`
+	Name        string `json:"name"`
+	NetBlock    string `json:"netblock"`
+	ProvideDHCP bool   `json:"provide_dhcp"`
+	ProvideNAT  bool   `json:"provide_nat"`
+
+	nextAddress int
+}
+
+type mockDiskSpec struct {
+	Base string `json:"base"`
+	Size int    `json:"size"`
+	Bus  string `json:"bus"`
+	Type string `json:"type"`
+}
+
+type mockVideoSpec struct {
+	Model  string `json:"model"`
+	Memory int    `json:"memory"`
+}
+
+type mockNetworkSpec struct {
+	NetworkUUID string `json:"network_uuid"`
+	Address     string `json:"address"`
+	MACAddress  string `json:"macaddress"`
+	Model       string `json:"model"`
+}
+
+type mockInstance struct {
+	mockObject
+	UUID        string         `json:"uuid"`
+	Name        string         `json:"name"`
+	CPUs        int            `json:"cpus"`
+	Memory      int            `json:"memory"`
+	DiskSpecs   []mockDiskSpec `json:"disk_spec"`
+	Video       mockVideoSpec  `json:"video"`
+	SSHKey      string         `json:"ssh_key"`
+	UserData    string         `json:"user_data"`
+	Node        string         `json:"node"`
+	ConsolePort int            `json:"console_port"`
+	VDIPort     int            `json:"vdi_port"`
+	PowerState  string         `json:"power_state"`
+
+	// powerPending are the power states the instance moves through on
+	// subsequent reads, and reboots the number of reboots
+	powerPending []string
+	reboots      int
+}
+
+type mockInterface struct {
+	mockObject
+	UUID         string `json:"uuid"`
+	NetworkUUID  string `json:"network_uuid"`
+	InstanceUUID string `json:"instance_uuid"`
+	MACAddress   string `json:"macaddr"`
+	IPv4         string `json:"ipv4"`
+	Order        int    `json:"order"`
+	Model        string `json:"model"`
+	Floating     string `json:"floating"`
+}
+
+type mockImage struct {
+	mockObject
+	URL      string `json:"url"`
+	Node     string `json:"node"`
+	Checksum string `json:"checksum"`
+	Size     int64  `json:"size"`
+}
+
+type mockSnapshot struct {
+	mockObject
+	UUID         string  `json:"uuid"`
+	Device       string  `json:"device"`
+	Created      float64 `json:"created"`
+	instanceUUID string
+}
+
+// mockFailure makes matching requests fail with an HTTP status.
+type mockFailure struct {
+	method string
+	path   string
+	status int
+	count  int
+}
+
+// newMockServer starts a fake Shaken Fist API server. The system namespace
+// can be authenticated to with the key.
+func newMockServer(systemKey string) *mockServer {
+	s := &mockServer{
+		nodes: []string{"sf-1", "sf-2", "sf-3"},
+		namespaces: map[string]*mockNamespace{
+			"system": {
+				keys:     map[string]string{"deploy": systemKey},
+				metadata: map[string]string{},
+			},
+		},
+		tokens:     map[string]string{},
+		networks:   map[string]*mockNetwork{},
+		instances:  map[string]*mockInstance{},
+		interfaces: map[string]*mockInterface{},
+		images:     map[string]*mockImage{},
+		snapshots:  map[string]*mockSnapshot{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// failRequests makes the next count requests with the method and a path
+// starting with path fail with the HTTP status. A negative count fails all
+// matching requests.
+func (s *mockServer) failRequests(method, path string, status, count int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.failures = append(s.failures, &mockFailure{
+		method: method,
+		path:   path,
+		status: status,
+		count:  count,
+	})
+}
+
+// instanceReboots returns the number of times the instance was rebooted.
+func (s *mockServer) instanceReboots(uuid string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if inst, ok := s.instances[uuid]; ok {
+		return inst.reboots
+	}
+	return 0
+}
+
+// mockError is the JSON error body returned by the Shaken Fist API.
+type mockError struct {
+	Error  string `json:"error"`
+	Status int    `json:"status"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, format string,
+	a ...interface{}) {
+
+	writeJSON(w, status, mockError{
+		Error:  fmt.Sprintf(format, a...),
+		Status: status,
+	})
+}
+
+func (s *mockServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if status := s.injectedFailure(r); status != 0 {
+		writeError(w, status, "injected failure")
+		return
+	}
+
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	if r.Method == http.MethodPost && len(path) == 1 && path[0] == "auth" {
+		s.authenticate(w, r)
+		return
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	namespace, ok := s.tokens[token]
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	switch {
+	case len(path) >= 2 && path[0] == "auth" && path[1] == "namespaces":
+		s.serveNamespaces(w, r, namespace, path[2:])
+	case path[0] == "nodes":
+		s.serveNodes(w, r)
+	case path[0] == "snapshots":
+		s.serveSnapshots(w, r, namespace, path[1:])
+	case path[0] == "images":
+		s.serveImages(w, r)
+	case path[0] == "networks":
+		s.serveNetworks(w, r, namespace, path[1:])
+	case path[0] == "instances":
+		s.serveInstances(w, r, namespace, path[1:])
+	case path[0] == "interfaces":
+		s.serveInterfaces(w, r, namespace, path[1:])
+	default:
+		writeError(w, http.StatusNotFound, "unknown path %s", r.URL.Path)
+	}
+}
+
+// injectedFailure returns the status of the first failure matching the
+// request, or zero.
+func (s *mockServer) injectedFailure(r *http.Request) int {
+	for i, f := range s.failures {
+		if f.method != r.Method || !strings.HasPrefix(r.URL.Path, f.path) {
+			continue
+		}
+		if f.count > 0 {
+			f.count--
+			if f.count == 0 {
+				s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			}
+		}
+		return f.status
+	}
+	return 0
+}
+
+func (s *mockServer) authenticate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Namespace string `json:"namespace"`
+		Key       string `json:"key"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request: %v", err)
+		return
+	}
+
+	if ns, ok := s.namespaces[req.Namespace]; ok {
+		for _, key := range ns.keys {
+			if key == req.Key {
+				token := newMockUUID()
+				s.tokens[token] = req.Namespace
+				writeJSON(w, http.StatusOK,
+					map[string]string{"access_token": token})
+				return
+			}
+		}
+	}
+	writeError(w, http.StatusUnauthorized, "unauthorized")
+}
+
+// visible reports whether an object in the namespace can be seen by a caller
+// authenticated to the caller namespace.
+func visible(caller, namespace string) bool {
+	return caller == "system" || caller == namespace
+}
+
+func (s *mockServer) serveNamespaces(w http.ResponseWriter, r *http.Request,
+	caller string, path []string) {
+
+	if len(path) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			names := []string{}
+			for name := range s.namespaces {
+				if visible(caller, name) {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+			writeJSON(w, http.StatusOK, names)
+
+		case http.MethodPost:
+			var req struct {
+				Namespace string `json:"namespace"`
+				KeyName   string `json:"key_name"`
+				Key       string `json:"key"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid request: %v", err)
+				return
+			}
+			if caller != "system" {
+				writeError(w, http.StatusUnauthorized,
+					"only the system namespace can create namespaces")
+				return
+			}
+			ns, ok := s.namespaces[req.Namespace]
+			if !ok {
+				ns = &mockNamespace{
+					keys:     map[string]string{},
+					metadata: map[string]string{},
+				}
+				s.namespaces[req.Namespace] = ns
+			}
+			if req.KeyName != "" {
+				ns.keys[req.KeyName] = req.Key
+			}
+			writeJSON(w, http.StatusOK, req.Namespace)
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+
+	name := path[0]
+	ns, ok := s.namespaces[name]
+	if !ok || !visible(caller, name) {
+		writeError(w, http.StatusNotFound, "namespace not found")
+		return
+	}
+
+	switch {
+	case len(path) == 1 && r.Method == http.MethodDelete:
+		if name == "system" {
+			writeError(w, http.StatusForbidden,
+				"you cannot delete the system namespace")
+			return
+		}
+		for _, n := range s.networks {
+			if n.Namespace == name && n.State != "deleted" {
+				writeError(w, http.StatusBadRequest,
+					"you cannot delete a namespace with networks")
+				return
+			}
+		}
+		for _, i := range s.instances {
+			if i.Namespace == name && i.State != "deleted" {
+				writeError(w, http.StatusBadRequest,
+					"you cannot delete a namespace with instances")
+				return
+			}
+		}
+		delete(s.namespaces, name)
+		writeJSON(w, http.StatusOK, nil)
+
+	case len(path) >= 2 && path[1] == "keys":
+		s.serveNamespaceKeys(w, r, ns, path[2:])
+
+	case len(path) >= 2 && path[1] == "metadata":
+		serveMetadata(w, r, ns.metadata, path[2:])
+
+	default:
+		writeError(w, http.StatusNotFound, "unknown path %s", r.URL.Path)
+	}
+}
+
+func (s *mockServer) serveNamespaceKeys(w http.ResponseWriter,
+	r *http.Request, ns *mockNamespace, path []string) {
+
+	var req struct {
+		KeyName string `json:"key_name"`
+		Key     string `json:"key"`
+	}
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request: %v", err)
+			return
+		}
+	}
+
+	if len(path) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			names := []string{}
+			for name := range ns.keys {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			writeJSON(w, http.StatusOK, names)
+
+		case http.MethodPost:
+			ns.keys[req.KeyName] = req.Key
+			writeJSON(w, http.StatusOK, req.KeyName)
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+
+	keyname := path[0]
+	if _, ok := ns.keys[keyname]; !ok {
+		writeError(w, http.StatusNotFound, "key not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		ns.keys[keyname] = req.Key
+		writeJSON(w, http.StatusOK, keyname)
+	case http.MethodDelete:
+		delete(ns.keys, keyname)
+		writeJSON(w, http.StatusOK, nil)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// serveMetadata handles the metadata of a namespace, network or instance.
+func serveMetadata(w http.ResponseWriter, r *http.Request,
+	metadata map[string]string, path []string) {
+
+	if len(path) == 0 {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeJSON(w, http.StatusOK, metadata)
+		return
+	}
+
+	key := path[0]
+	switch r.Method {
+	case http.MethodPost, http.MethodPut:
+		var req struct {
+			Value string `json:"value"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request: %v", err)
+			return
+		}
+		metadata[key] = req.Value
+		writeJSON(w, http.StatusOK, nil)
+
+	case http.MethodDelete:
+		if _, ok := metadata[key]; !ok {
+			writeError(w, http.StatusNotFound, "metadata key not found")
+			return
+		}
+		delete(metadata, key)
+		writeJSON(w, http.StatusOK, nil)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *mockServer) serveNodes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	type mockNode struct {
+		Name     string  `json:"name"`
+		IP       string  `json:"ip"`
+		LastSeen float64 `json:"lastseen"`
+		Version  string  `json:"version"`
+	}
+
+	nodes := []mockNode{}
+	for i, name := range s.nodes {
+		nodes = append(nodes, mockNode{
+			Name:     name,
+			IP:       fmt.Sprintf("192.168.1.%d", i+1),
+			LastSeen: float64(time.Now().Unix()),
+			Version:  "0.4.0",
+		})
+	}
+	writeJSON(w, http.StatusOK, nodes)
+}
+
+func (s *mockServer) serveImages(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		node := r.URL.Query().Get("node")
+		images := []*mockImage{}
+		for _, img := range s.images {
+			if node == "" || img.Node == node {
+				img.advance()
+				images = append(images, img)
+			}
+		}
+		sort.Slice(images, func(i, j int) bool {
+			return images[i].Node+images[i].URL < images[j].Node+images[j].URL
+		})
+		writeJSON(w, http.StatusOK, images)
+
+	case http.MethodPost:
+		var req struct {
+			URL  string `json:"url"`
+			Node string `json:"node"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request: %v", err)
+			return
+		}
+
+		node := req.Node
+		if node == "" {
+			node = s.nodes[0]
+		}
+		if !containsString(s.nodes, node) {
+			writeError(w, http.StatusNotFound, "node %s not found", node)
+			return
+		}
+
+		key := node + "/" + req.URL
+		if _, ok := s.images[key]; !ok {
+			sum := sha256.Sum256([]byte(req.URL))
+			s.images[key] = &mockImage{
+				mockObject: mockObject{
+					State:   "initial",
+					pending: []string{"creating", "created"},
+				},
+				URL:      req.URL,
+				Node:     node,
+				Checksum: hex.EncodeToString(sum[:]),
+				Size:     int64(len(req.URL)) * 1024 * 1024,
+			}
+		}
+		writeJSON(w, http.StatusOK, nil)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *mockServer) serveInstanceSnapshots(w http.ResponseWriter,
+	r *http.Request, inst *mockInstance) {
+
+	switch r.Method {
+	case http.MethodGet:
+		snapshots := []*mockSnapshot{}
+		for _, snap := range s.snapshots {
+			if snap.instanceUUID == inst.UUID {
+				snap.advance()
+				snapshots = append(snapshots, snap)
+			}
+		}
+		sort.Slice(snapshots, func(i, j int) bool {
+			return snapshots[i].UUID < snapshots[j].UUID
+		})
+		writeJSON(w, http.StatusOK, snapshots)
+
+	case http.MethodPost:
+		var req struct {
+			All    bool   `json:"all"`
+			Device string `json:"device"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request: %v", err)
+			return
+		}
+
+		// Disks are named vda, vdb and so on
+		var devices []string
+		for i := range inst.DiskSpecs {
+			device := fmt.Sprintf("vd%c", 'a'+i)
+			if req.All || device == req.Device ||
+				(req.Device == "" && i == 0) {
+				devices = append(devices, device)
+			}
+		}
+		if len(devices) == 0 {
+			writeError(w, http.StatusNotFound, "device %s not found",
+				req.Device)
+			return
+		}
+
+		created := map[string]*mockSnapshot{}
+		for _, device := range devices {
+			snap := &mockSnapshot{
+				mockObject: mockObject{
+					State:     "initial",
+					Namespace: inst.Namespace,
+					pending:   []string{"creating", "created"},
+				},
+				UUID:         newMockUUID(),
+				Device:       device,
+				Created:      float64(time.Now().Unix()),
+				instanceUUID: inst.UUID,
+			}
+			s.snapshots[snap.UUID] = snap
+			created[device] = snap
+		}
+		writeJSON(w, http.StatusOK, created)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *mockServer) serveSnapshots(w http.ResponseWriter, r *http.Request,
+	caller string, path []string) {
+
+	if len(path) != 1 || r.Method != http.MethodDelete {
+		writeError(w, http.StatusNotFound, "unknown path %s", r.URL.Path)
+		return
+	}
+
+	snap, ok := s.snapshots[path[0]]
+	if !ok || snap.State == "deleted" || !visible(caller, snap.Namespace) {
+		writeError(w, http.StatusNotFound, "snapshot not found")
+		return
+	}
+	snap.State = "deleted"
+	snap.pending = nil
+	writeJSON(w, http.StatusOK, nil)
+}
+
+// placeInstance returns the requested node if it exists, otherwise the node
+// running the fewest instances.
+func (s *mockServer) placeInstance(placedOn string) (string, bool) {
+	load := map[string]int{}
+	for _, i := range s.instances {
+		if i.State != "deleted" {
+			load[i.Node]++
+		}
+	}
+
+	best := ""
+	for _, name := range s.nodes {
+		if placedOn != "" {
+			if name == placedOn {
+				return name, true
+			}
+			continue
+		}
+		if best == "" || load[name] < load[best] {
+			best = name
+		}
+	}
+	return best, best != ""
+}
+
+func (s *mockServer) serveNetworks(w http.ResponseWriter, r *http.Request,
+	caller string, path []string) {
+
+	if len(path) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			networks := []*mockNetwork{}
+			for _, n := range s.networks {
+				if visible(caller, n.Namespace) {
+					n.advance()
+					networks = append(networks, n)
+				}
+			}
+			sort.Slice(networks, func(i, j int) bool {
+				return networks[i].UUID < networks[j].UUID
+			})
+			writeJSON(w, http.StatusOK, networks)
+
+		case http.MethodPost:
+			s.createNetwork(w, r, caller)
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+
+	n, ok := s.networks[path[0]]
+	if !ok || !visible(caller, n.Namespace) {
+		writeError(w, http.StatusNotFound, "network not found")
+		return
+	}
+
+	switch {
+	case len(path) == 1 && r.Method == http.MethodGet:
+		n.advance()
+		writeJSON(w, http.StatusOK, n)
+
+	case len(path) == 1 && r.Method == http.MethodDelete:
+		if n.State == "deleted" || n.State == "deleting" {
+			writeError(w, http.StatusNotFound, "network not found")
+			return
+		}
+		for _, iface := range s.interfaces {
+			if iface.NetworkUUID == n.UUID && iface.State != "deleted" {
+				writeError(w, http.StatusForbidden,
+					"you cannot delete a network in use by instances")
+				return
+			}
+		}
+		n.State = "deleting"
+		n.pending = []string{"deleted"}
+		writeJSON(w, http.StatusOK, n)
+
+	case len(path) >= 2 && path[1] == "metadata":
+		serveMetadata(w, r, n.metadata, path[2:])
+
+	default:
+		writeError(w, http.StatusNotFound, "unknown path %s", r.URL.Path)
+	}
+}
+
+func (s *mockServer) createNetwork(w http.ResponseWriter, r *http.Request,
+	caller string) {
+
+	var req struct {
+		NetBlock    string `json:"netblock"`
+		ProvideDHCP bool   `json:"provide_dhcp"`
+		ProvideNAT  bool   `json:"provide_nat"`
+		Name        string `json:"name"`
+		Namespace   string `json:"namespace"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request: %v", err)
+		return
+	}
+	if _, _, err := net.ParseCIDR(req.NetBlock); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid netblock: %v", err)
+		return
+	}
+
+	namespace := caller
+	if req.Namespace != "" && caller == "system" {
+		namespace = req.Namespace
+	}
+
+	n := &mockNetwork{
+		mockObject: mockObject{
+			State:     "initial",
+			Namespace: namespace,
+			pending:   []string{"initial", "created"},
+			metadata:  map[string]string{},
+		},
+		UUID:        newMockUUID(),
+		Name:        req.Name,
+		NetBlock:    req.NetBlock,
+		ProvideDHCP: req.ProvideDHCP,
+		ProvideNAT:  req.ProvideNAT,
+		nextAddress: 2,
+	}
+	s.networks[n.UUID] = n
+	writeJSON(w, http.StatusOK, n)
+}
+
+func (s *mockServer) serveInstances(w http.ResponseWriter, r *http.Request,
+	caller string, path []string) {
+
+	if len(path) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			instances := []*mockInstance{}
+			for _, i := range s.instances {
+				if visible(caller, i.Namespace) {
+					i.advance()
+					instances = append(instances, i)
+				}
+			}
+			sort.Slice(instances, func(a, b int) bool {
+				return instances[a].UUID < instances[b].UUID
+			})
+			writeJSON(w, http.StatusOK, instances)
+
+		case http.MethodPost:
+			s.createInstance(w, r, caller)
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+
+	inst, ok := s.instances[path[0]]
+	if !ok || !visible(caller, inst.Namespace) {
+		writeError(w, http.StatusNotFound, "instance not found")
+		return
+	}
+
+	if len(path) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			inst.advance()
+			if len(inst.powerPending) > 0 {
+				inst.PowerState = inst.powerPending[0]
+				inst.powerPending = inst.powerPending[1:]
+			}
+			writeJSON(w, http.StatusOK, inst)
+
+		case http.MethodDelete:
+			if inst.State == "deleted" || inst.State == "deleting" {
+				writeError(w, http.StatusNotFound, "instance not found")
+				return
+			}
+			inst.State = "deleting"
+			inst.pending = []string{"deleted"}
+			inst.PowerState = "off"
+			for _, iface := range s.interfaces {
+				if iface.InstanceUUID == inst.UUID {
+					iface.State = "deleted"
+				}
+			}
+			writeJSON(w, http.StatusOK, inst)
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+
+	switch path[1] {
+	case "metadata":
+		serveMetadata(w, r, inst.metadata, path[2:])
+		return
+
+	case "snapshot":
+		s.serveInstanceSnapshots(w, r, inst)
+		return
+
+	case "interfaces":
+		interfaces := []*mockInterface{}
+		for _, iface := range s.interfaces {
+			if iface.InstanceUUID == inst.UUID {
+				interfaces = append(interfaces, iface)
+			}
+		}
+		sort.Slice(interfaces, func(a, b int) bool {
+			return interfaces[a].Order < interfaces[b].Order
+		})
+		writeJSON(w, http.StatusOK, interfaces)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if inst.State != "created" {
+		writeError(w, http.StatusNotAcceptable,
+			"instance %s is not ready (%s)", inst.UUID, inst.State)
+		return
+	}
+
+	transitions := map[string]struct{ from, to string }{
+		"poweron":    {"off", "on"},
+		"poweroff":   {"", "off"},
+		"pause":      {"on", "paused"},
+		"unpause":    {"paused", "on"},
+		"rebootsoft": {"on", "on"},
+		"reboothard": {"on", "on"},
+	}
+	tr, ok := transitions[path[1]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown path %s", r.URL.Path)
+		return
+	}
+	if tr.from != "" && inst.PowerState != tr.from {
+		writeError(w, http.StatusNotAcceptable,
+			"instance %s is %s", inst.UUID, inst.PowerState)
+		return
+	}
+	inst.PowerState = tr.to
+
+	// A rebooting instance is seen to power on again
+	if strings.HasPrefix(path[1], "reboot") {
+		inst.powerPending = []string{"transition-to-on", "on"}
+		inst.reboots++
+	}
+	writeJSON(w, http.StatusOK, inst)
+}
+
+func (s *mockServer) createInstance(w http.ResponseWriter, r *http.Request,
+	caller string) {
+
+	var req struct {
+		Name      string            `json:"name"`
+		CPUs      int               `json:"cpus"`
+		Memory    int               `json:"memory"`
+		Networks  []mockNetworkSpec `json:"network"`
+		Disks     []mockDiskSpec    `json:"disk"`
+		SSHKey    string            `json:"ssh_key"`
+		UserData  string            `json:"user_data"`
+		Video     mockVideoSpec     `json:"video"`
+		Namespace string            `json:"namespace"`
+		PlacedOn  string            `json:"placed_on"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request: %v", err)
+		return
+	}
+
+	namespace := caller
+	if req.Namespace != "" && caller == "system" {
+		namespace = req.Namespace
+	}
+
+	for _, spec := range req.Networks {
+		n, ok := s.networks[spec.NetworkUUID]
+		if !ok || n.State != "created" || !visible(namespace, n.Namespace) {
+			writeError(w, http.StatusNotFound, "network %s not found",
+				spec.NetworkUUID)
+			return
+		}
+	}
+
+	node, ok := s.placeInstance(req.PlacedOn)
+	if !ok {
+		writeError(w, http.StatusNotFound, "node %s not found", req.PlacedOn)
+		return
+	}
+
+	inst := &mockInstance{
+		mockObject: mockObject{
+			State:     "initial",
+			Namespace: namespace,
+			pending:   []string{"preflight", "creating", "created"},
+			metadata:  map[string]string{},
+		},
+		UUID:        newMockUUID(),
+		Name:        req.Name,
+		CPUs:        req.CPUs,
+		Memory:      req.Memory,
+		DiskSpecs:   req.Disks,
+		Video:       req.Video,
+		SSHKey:      req.SSHKey,
+		UserData:    req.UserData,
+		Node:        node,
+		ConsolePort: 30000 + len(s.instances),
+		VDIPort:     40000 + len(s.instances),
+		PowerState:  "on",
+	}
+
+	for order, spec := range req.Networks {
+		n := s.networks[spec.NetworkUUID]
+
+		address := spec.Address
+		if address == "" {
+			address = n.allocateAddress()
+		}
+		mac := spec.MACAddress
+		if mac == "" {
+			mac = newMockMAC()
+		}
+		model := spec.Model
+		if model == "" {
+			model = "virtio"
+		}
+
+		iface := &mockInterface{
+			mockObject: mockObject{
+				State:     "created",
+				Namespace: namespace,
+			},
+			UUID:         newMockUUID(),
+			NetworkUUID:  n.UUID,
+			InstanceUUID: inst.UUID,
+			MACAddress:   mac,
+			IPv4:         address,
+			Order:        order,
+			Model:        model,
+		}
+		s.interfaces[iface.UUID] = iface
+	}
+
+	s.instances[inst.UUID] = inst
+	writeJSON(w, http.StatusOK, inst)
+}
+
+// allocateAddress returns the next unused address in the network.
+func (n *mockNetwork) allocateAddress() string {
+	_, ipnet, _ := net.ParseCIDR(n.NetBlock)
+	ip := ipnet.IP.To4()
+	if ip == nil {
+		return ""
+	}
+
+	addr := binary.BigEndian.Uint32(ip) + uint32(n.nextAddress)
+	n.nextAddress++
+
+	out := make(net.IP, 4)
+	binary.BigEndian.PutUint32(out, addr)
+	return out.String()
+}
+
+func (s *mockServer) serveInterfaces(w http.ResponseWriter, r *http.Request,
+	caller string, path []string) {
+
+	if len(path) == 0 {
+		writeError(w, http.StatusNotFound, "unknown path %s", r.URL.Path)
+		return
+	}
+
+	iface, ok := s.interfaces[path[0]]
+	if !ok || !visible(caller, iface.Namespace) {
+		writeError(w, http.StatusNotFound, "interface not found")
+		return
+	}
+
+	switch {
+	case len(path) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, iface)
+
+	case len(path) == 2 && r.Method == http.MethodPost && path[1] == "float":
+		if iface.Floating == "" {
+			s.nextFloat++
+			iface.Floating = fmt.Sprintf("192.168.200.%d", s.nextFloat)
+		}
+		writeJSON(w, http.StatusOK, iface)
+
+	case len(path) == 2 && r.Method == http.MethodPost && path[1] == "defloat":
+		iface.Floating = ""
+		writeJSON(w, http.StatusOK, iface)
+
+	default:
+		writeError(w, http.StatusNotFound, "unknown path %s", r.URL.Path)
+	}
+}
+
+// newMockUUID returns a random UUID.
+func newMockUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" +
+		h[20:]
+}
+
+// newMockMAC returns a random locally administered MAC address.
+func newMockMAC() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	b[0] = (b[0] | 0x02) & 0xfe
+
+	return net.HardwareAddr(b).String()
+}
+
+func TestUnitMockServer(t *testing.T) {
+	s := newMockServer("mockkey")
+	defer s.Close()
+
+	request := func(method, path, token string, body interface{},
+		out interface{}) int {
+
+		data, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, s.URL+path, bytes.NewReader(data))
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := s.Client().Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+
+		if out != nil {
+			json.NewDecoder(resp.Body).Decode(out)
+		}
+		return resp.StatusCode
+	}
+
+	var auth map[string]string
+	status := request("POST", "/auth", "",
+		map[string]string{"namespace": "system", "key": "wrong"}, nil)
+	if status != http.StatusUnauthorized {
+		t.Errorf("Authentication with a bad key returned %d", status)
+	}
+	request("POST", "/auth", "",
+		map[string]string{"namespace": "system", "key": "mockkey"}, &auth)
+	token := auth["access_token"]
+
+	if status := request("GET", "/networks", "bad", nil, nil); status !=
+		http.StatusUnauthorized {
+		t.Errorf("Request with a bad token returned %d", status)
+	}
+
+	var network mockNetwork
+	request("POST", "/networks", token, map[string]interface{}{
+		"netblock": "10.0.0.0/24",
+		"name":     "mock",
+	}, &network)
+
+	// Networks become created after being read
+	states := []string{}
+	for i := 0; i < 3; i++ {
+		var n mockNetwork
+		request("GET", "/networks/"+network.UUID, token, nil, &n)
+		states = append(states, n.State)
+	}
+	if strings.Join(states, ",") != "initial,created,created" {
+		t.Errorf("Unexpected network states: %v", states)
+	}
+
+	// Injected failures are returned the requested number of times
+	s.failRequests("GET", "/networks/", http.StatusServiceUnavailable, 1)
+	if status := request("GET", "/networks/"+network.UUID, token,
+		nil, nil); status != http.StatusServiceUnavailable {
+		t.Errorf("Injected failure returned %d", status)
+	}
+	if status := request("GET", "/networks/"+network.UUID, token,
+		nil, nil); status != http.StatusOK {
+		t.Errorf("Request after injected failure returned %d", status)
+	}
+
+	// Instances are given an address on each network
+	var inst mockInstance
+	request("POST", "/instances", token, map[string]interface{}{
+		"name":    "mock",
+		"network": []map[string]string{{"network_uuid": network.UUID}},
+	}, &inst)
+
+	var interfaces []mockInterface
+	request("GET", "/instances/"+inst.UUID+"/interfaces", token, nil,
+		&interfaces)
+	if len(interfaces) != 1 || interfaces[0].IPv4 != "10.0.0.2" {
+		t.Errorf("Unexpected instance interfaces: %+v", interfaces)
+	}
+
+	// Power operations are refused until the instance is created
+	if status := request("POST", "/instances/"+inst.UUID+"/poweroff", token,
+		nil, nil); status != http.StatusNotAcceptable {
+		t.Errorf("Power off of an instance being created returned %d", status)
+	}
+
+	// A network in use cannot be deleted
+	if status := request("DELETE", "/networks/"+network.UUID, token,
+		nil, nil); status != http.StatusForbidden {
+		t.Errorf("Delete of a network in use returned %d", status)
+	}
+}
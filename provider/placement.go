@@ -0,0 +1,209 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	client "github.com/shakenfist/client-go"
+)
+
+// Instance metadata keys holding the placement groups of an instance. Any
+// instance with the metadata key set is a member of the group, including
+// instances not managed by Terraform.
+const (
+	affinityGroupKey     = "affinity_group"
+	antiAffinityGroupKey = "anti_affinity_group"
+)
+
+// placementLock serialises placement decisions, so that instances created in
+// parallel see the placement groups of each other.
+var placementLock sync.Mutex
+
+// instancePlacement is the placement block of an instance.
+type instancePlacement struct {
+	node              string
+	affinityGroup     string
+	antiAffinityGroup string
+}
+
+// expandPlacement returns the placement block of the instance resource, or
+// nil if no placement is configured.
+func expandPlacement(d *schema.ResourceData) *instancePlacement {
+	conf := d.Get("placement").([]interface{})
+	if len(conf) == 0 || conf[0] == nil {
+		return nil
+	}
+
+	p := conf[0].(map[string]interface{})
+	return &instancePlacement{
+		node:              p["node"].(string),
+		affinityGroup:     p["affinity_group"].(string),
+		antiAffinityGroup: p["anti_affinity_group"].(string),
+	}
+}
+
+// metadata returns the instance metadata recording the placement groups.
+func (p *instancePlacement) metadata() map[string]string {
+	metadata := map[string]string{}
+	if p.affinityGroup != "" {
+		metadata[affinityGroupKey] = p.affinityGroup
+	}
+	if p.antiAffinityGroup != "" {
+		metadata[antiAffinityGroupKey] = p.antiAffinityGroup
+	}
+	return metadata
+}
+
+// nodePlacement describes the instances running on a node.
+type nodePlacement struct {
+	instances    int
+	affinity     int
+	antiAffinity int
+}
+
+// choosePlacementNode returns the node the instance must be created on, or
+// an empty string if the Shaken Fist scheduler can choose any node.
+func choosePlacementNode(apiClient *client.Client,
+	p *instancePlacement) (string, error) {
+
+	nodes, err := apiClient.GetNodes()
+	if err != nil {
+		return "", fmt.Errorf("Unable to retrieve nodes: %v", err)
+	}
+
+	placements := map[string]*nodePlacement{}
+	for _, n := range nodes {
+		placements[n.Name] = &nodePlacement{}
+	}
+
+	if p.affinityGroup != "" || p.antiAffinityGroup != "" {
+		instances, err := apiClient.GetInstances()
+		if err != nil {
+			return "", fmt.Errorf("Unable to retrieve instances: %v", err)
+		}
+
+		for _, inst := range instances {
+			np, ok := placements[inst.Node]
+			if !ok || inst.State == "deleted" {
+				continue
+			}
+			np.instances++
+
+			metadata, err := apiClient.GetMetadata(
+				client.TypeInstance, inst.UUID)
+			if err != nil {
+				if isNotFound(err) {
+					continue
+				}
+				return "", fmt.Errorf(
+					"Unable to retrieve instance metadata: %v", err)
+			}
+			if p.affinityGroup != "" &&
+				metadata[affinityGroupKey] == p.affinityGroup {
+				np.affinity++
+			}
+			if p.antiAffinityGroup != "" &&
+				metadata[antiAffinityGroupKey] == p.antiAffinityGroup {
+				np.antiAffinity++
+			}
+		}
+	}
+
+	return selectPlacementNode(p, placements)
+}
+
+// selectPlacementNode chooses the node for the instance from the instances
+// running on each node. An explicit node is checked against the placement
+// groups. Otherwise an instance with an affinity group is placed with the
+// most members of its group, and an instance with an anti-affinity group on
+// the least busy node without members of its group.
+func selectPlacementNode(p *instancePlacement,
+	placements map[string]*nodePlacement) (string, error) {
+
+	// Group members are only counted for the groups of the instance
+	excluded := func(np *nodePlacement) bool {
+		return p.antiAffinityGroup != "" && np.antiAffinity > 0
+	}
+
+	var names, affinityNodes []string
+	for name, np := range placements {
+		names = append(names, name)
+		if p.affinityGroup != "" && np.affinity > 0 {
+			affinityNodes = append(affinityNodes, name)
+		}
+	}
+	sort.Strings(names)
+	sort.Strings(affinityNodes)
+
+	if p.node != "" {
+		np, ok := placements[p.node]
+		if !ok {
+			return "", fmt.Errorf("Node %s is not a Shaken Fist node, "+
+				"nodes are: %s", p.node, strings.Join(names, ", "))
+		}
+		if excluded(np) {
+			return "", fmt.Errorf("Node %s runs an instance in "+
+				"anti-affinity group %s", p.node, p.antiAffinityGroup)
+		}
+		if len(affinityNodes) > 0 && np.affinity == 0 {
+			return "", fmt.Errorf("Affinity group %s runs on %s, not "+
+				"node %s", p.affinityGroup,
+				strings.Join(affinityNodes, ", "), p.node)
+		}
+		return p.node, nil
+	}
+
+	// Prefer the node with the most instances in the affinity group
+	best := ""
+	for _, name := range affinityNodes {
+		np := placements[name]
+		if excluded(np) {
+			continue
+		}
+		if best == "" || np.affinity > placements[best].affinity {
+			best = name
+		}
+	}
+	if best != "" {
+		return best, nil
+	}
+	if len(affinityNodes) > 0 {
+		return "", fmt.Errorf("Affinity group %s only runs on nodes with "+
+			"instances in anti-affinity group %s", p.affinityGroup,
+			p.antiAffinityGroup)
+	}
+
+	if p.antiAffinityGroup == "" {
+		return "", nil
+	}
+
+	for _, name := range names {
+		np := placements[name]
+		if excluded(np) {
+			continue
+		}
+		if best == "" || np.instances < placements[best].instances {
+			best = name
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("Every node runs an instance in "+
+			"anti-affinity group %s", p.antiAffinityGroup)
+	}
+	return best, nil
+}
+
+// withoutPlacementMetadata returns the instance metadata without the keys
+// recording the placement groups, which are managed by the placement block.
+func withoutPlacementMetadata(metadata map[string]string) map[string]string {
+	filtered := map[string]string{}
+	for k, v := range metadata {
+		if k != affinityGroupKey && k != antiAffinityGroupKey {
+			filtered[k] = v
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"testing"
+)
+
+func TestUnitSelectPlacementNode(t *testing.T) {
+	// Node sf-1 runs two instances in the db anti-affinity group and one in
+	// the web affinity group, sf-2 runs one instance and sf-3 none.
+	placements := func() map[string]*nodePlacement {
+		return map[string]*nodePlacement{
+			"sf-1": {instances: 3, affinity: 1, antiAffinity: 2},
+			"sf-2": {instances: 1},
+			"sf-3": {},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		placement instancePlacement
+		expected  string
+		err       bool
+	}{
+		{"no groups", instancePlacement{}, "", false},
+		{"node", instancePlacement{node: "sf-2"}, "sf-2", false},
+		{"unknown node", instancePlacement{node: "sf-9"}, "", true},
+		{"affinity",
+			instancePlacement{affinityGroup: "web"}, "sf-1", false},
+		{"affinity node",
+			instancePlacement{node: "sf-1", affinityGroup: "web"},
+			"sf-1", false},
+		{"affinity other node",
+			instancePlacement{node: "sf-2", affinityGroup: "web"}, "", true},
+		{"anti-affinity",
+			instancePlacement{antiAffinityGroup: "db"}, "sf-3", false},
+		{"anti-affinity node",
+			instancePlacement{node: "sf-1", antiAffinityGroup: "db"}, "", true},
+		{"conflicting groups",
+			instancePlacement{affinityGroup: "web", antiAffinityGroup: "db"},
+			"", true},
+	}
+
+	for _, test := range tests {
+		p := test.placement
+		actual, err := selectPlacementNode(&p, placements())
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected an error, got node %q",
+					test.name, actual)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		} else if actual != test.expected {
+			t.Errorf("%s: got node %q, expected %q",
+				test.name, actual, test.expected)
+		}
+	}
+
+	// Every node runs an instance in the anti-affinity group
+	full := placements()
+	full["sf-2"].antiAffinity = 1
+	full["sf-3"].antiAffinity = 1
+	p := instancePlacement{antiAffinityGroup: "db"}
+	if _, err := selectPlacementNode(&p, full); err == nil {
+		t.Errorf("Expected an error with no node available")
+	}
+}
@@ -3,33 +3,193 @@
 package provider
 
 import (
+	"context"
 	"fmt"
+	"sync"
+	"time"
 
-	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
-	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/logging"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	client "github.com/shakenfist/client-go"
 )
 
+// providerMeta is the configured provider, passed to resources and data
+// sources as their meta argument.
+type providerMeta struct {
+	client    *client.Client
+	namespace string
+
+	// serverURL and key create the clients sending creation options
+	serverURL string
+	key       string
+
+	lock          sync.Mutex
+	createClients map[createOptions]*client.Client
+}
+
+// objectNamespace returns the namespace argument of an instance or network
+// to be created, or an empty string to create it in the provider namespace.
+// Only the system namespace can create objects in other namespaces.
+func (m *providerMeta) objectNamespace(d *schema.ResourceData) (string,
+	error) {
+
+	namespace := d.Get("namespace").(string)
+	if namespace == "" || namespace == m.namespace {
+		return "", nil
+	}
+	if m.namespace != "system" {
+		return "", fmt.Errorf("Namespace %s can only be used when the "+
+			"provider is authenticated to the system namespace", namespace)
+	}
+	return namespace, nil
+}
+
+// createClient returns the client creating objects with the options. The
+// namespace is the one returned by objectNamespace. Clients sending options
+// are authenticated as the provider, and their requests carry the options.
+func (m *providerMeta) createClient(opts createOptions) (*client.Client,
+	error) {
+
+	if opts == (createOptions{}) {
+		return m.client, nil
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if c, ok := m.createClients[opts]; ok {
+		return c, nil
+	}
+	serverURL, err := createURL(m.serverURL, opts)
+	if err != nil {
+		return nil, err
+	}
+	c := client.NewClient(serverURL, m.namespace, m.key)
+	if m.createClients == nil {
+		m.createClients = map[createOptions]*client.Client{}
+	}
+	m.createClients[opts] = c
+	return c, nil
+}
+
 // Provider is the terraform provider interface
-func Provider() terraform.ResourceProvider {
+func Provider() *schema.Provider {
 	return &schema.Provider{
 		Schema: map[string]*schema.Schema{
 			"server_url": {
 				Type:        schema.TypeString,
-				Required:    true,
+				Optional:    true,
 				DefaultFunc: schema.EnvDefaultFunc("SHAKENFIST_API_URL", ""),
 			},
 			"namespace": {
 				Type:        schema.TypeString,
-				Required:    true,
+				Optional:    true,
 				DefaultFunc: schema.EnvDefaultFunc("SHAKENFIST_NAMESPACE", ""),
 			},
 			"key": {
 				Type:        schema.TypeString,
-				Required:    true,
+				Optional:    true,
 				Sensitive:   true,
 				DefaultFunc: schema.EnvDefaultFunc("SHAKENFIST_KEY", ""),
 			},
+			"config_file": {
+				Type:     schema.TypeString,
+				Optional: true,
+				DefaultFunc: schema.EnvDefaultFunc(
+					"SHAKENFIST_CONFIG_FILE", ""),
+				Description: "Shaken Fist client config file " +
+					"used for settings not set by arguments or environment",
+			},
+			"profile": {
+				Type:     schema.TypeString,
+				Optional: true,
+				DefaultFunc: schema.EnvDefaultFunc(
+					"SHAKENFIST_PROFILE", ""),
+				Description: "Named profile in the " +
+					"Shaken Fist client config file",
+			},
+			"ca_cert_file": {
+				Type:     schema.TypeString,
+				Optional: true,
+				DefaultFunc: schema.EnvDefaultFunc(
+					"SHAKENFIST_CA_CERT_FILE", ""),
+				Description: "Path to a PEM encoded CA bundle " +
+					"used to verify the API server",
+			},
+			"ca_cert_pem": {
+				Type:     schema.TypeString,
+				Optional: true,
+				DefaultFunc: schema.EnvDefaultFunc(
+					"SHAKENFIST_CA_CERT_PEM", ""),
+				Description: "PEM encoded CA bundle " +
+					"used to verify the API server",
+			},
+			"client_cert": {
+				Type:     schema.TypeString,
+				Optional: true,
+				DefaultFunc: schema.EnvDefaultFunc(
+					"SHAKENFIST_CLIENT_CERT", ""),
+				Description: "PEM encoded client certificate, " +
+					"or the path to it",
+			},
+			"client_key": {
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+				DefaultFunc: schema.EnvDefaultFunc(
+					"SHAKENFIST_CLIENT_KEY", ""),
+				Description: "PEM encoded client certificate key, " +
+					"or the path to it",
+			},
+			"insecure": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				DefaultFunc: schema.EnvDefaultFunc(
+					"SHAKENFIST_INSECURE", false),
+				Description: "Do not verify the API server " +
+					"certificate, for development only",
+			},
+			"max_retries": {
+				Type:     schema.TypeInt,
+				Optional: true,
+				DefaultFunc: schema.EnvDefaultFunc(
+					"SHAKENFIST_MAX_RETRIES", 3),
+				Description: "Maximum number of times " +
+					"a failed API request is retried",
+			},
+			"retry_min_backoff": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "1s",
+				Description: "Minimum delay before " +
+					"retrying a failed API request",
+				ValidateDiagFunc: validateDuration,
+			},
+			"retry_max_backoff": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "30s",
+				Description: "Maximum delay before " +
+					"retrying a failed API request",
+				ValidateDiagFunc: validateDuration,
+			},
+			"max_requests_per_second": {
+				Type:     schema.TypeFloat,
+				Optional: true,
+				DefaultFunc: schema.EnvDefaultFunc(
+					"SHAKENFIST_MAX_REQUESTS_PER_SECOND", 0),
+				Description: "Maximum rate of API requests, " +
+					"0 is unlimited",
+			},
+			"max_concurrent_requests": {
+				Type:     schema.TypeInt,
+				Optional: true,
+				DefaultFunc: schema.EnvDefaultFunc(
+					"SHAKENFIST_MAX_CONCURRENT_REQUESTS", 0),
+				Description: "Maximum number of API requests " +
+					"in flight at once, 0 is unlimited",
+			},
 		},
 		ResourcesMap: map[string]*schema.Resource{
 			"shakenfist_namespace": resourceNamespace(),
@@ -37,26 +197,87 @@ func Provider() terraform.ResourceProvider {
 			"shakenfist_network":   resourceNetwork(),
 			"shakenfist_instance":  resourceInstance(),
 			"shakenfist_float":     resourceFloat(),
+			"shakenfist_image":     resourceImage(),
+
+			"shakenfist_instance_snapshot": resourceInstanceSnapshot(),
+			"shakenfist_metadata":          resourceMetadata(),
 		},
-		ConfigureFunc: providerConfigure,
+		DataSourcesMap: map[string]*schema.Resource{
+			"shakenfist_instance":  dataSourceInstance(),
+			"shakenfist_instances": dataSourceInstances(),
+			"shakenfist_images":    dataSourceImages(),
+			"shakenfist_network":   dataSourceNetwork(),
+			"shakenfist_networks":  dataSourceNetworks(),
+			"shakenfist_nodes":     dataSourceNodes(),
+		},
+		ConfigureContextFunc: providerConfigure,
 	}
 }
 
-func providerConfigure(d *schema.ResourceData) (interface{}, error) {
-	server_url := d.Get("server_url").(string)
-	namespace := d.Get("namespace").(string)
-	key := d.Get("key").(string)
+func providerConfigure(ctx context.Context,
+	d *schema.ResourceData) (interface{}, diag.Diagnostics) {
+
+	creds, err := resolveCredentials(clientConfig{
+		APIURL:    d.Get("server_url").(string),
+		Namespace: d.Get("namespace").(string),
+		Key:       d.Get("key").(string),
+	}, d.Get("config_file").(string), d.Get("profile").(string))
+	if err != nil {
+		return nil, diag.FromErr(err)
+	}
+	server_url, namespace, key := creds.APIURL, creds.Namespace, creds.Key
 
 	if server_url == "" {
-		return nil, fmt.Errorf(
+		return nil, diag.Errorf(
 			"Server URL not set, expecting \"http://<server>:<port>\"")
 	}
 	if namespace == "" {
-		return nil, fmt.Errorf("Namespace not set")
+		return nil, diag.Errorf("Namespace not set")
 	}
 	if key == "" {
-		return nil, fmt.Errorf("Access key not set")
+		return nil, diag.Errorf("Access key not set")
+	}
+
+	tls := tlsSettings{
+		caCertFile: d.Get("ca_cert_file").(string),
+		caCertPEM:  d.Get("ca_cert_pem").(string),
+		clientCert: d.Get("client_cert").(string),
+		clientKey:  d.Get("client_key").(string),
+		insecure:   d.Get("insecure").(bool),
+	}
+
+	// Durations are checked by validateDuration
+	minBackoff, _ := time.ParseDuration(d.Get("retry_min_backoff").(string))
+	maxBackoff, _ := time.ParseDuration(d.Get("retry_max_backoff").(string))
+	retry := retryPolicy{
+		maxRetries: d.Get("max_retries").(int),
+		minBackoff: minBackoff,
+		maxBackoff: maxBackoff,
+	}
+	if err := validateRetryPolicy(retry); err != nil {
+		return nil, diag.FromErr(err)
+	}
+
+	requestRate := d.Get("max_requests_per_second").(float64)
+	maxInFlight := d.Get("max_concurrent_requests").(int)
+	if requestRate < 0 || maxInFlight < 0 {
+		return nil, diag.Errorf("Request limits must not be negative")
+	}
+
+	err = registerAPITransport(server_url, apiSettings{
+		tls:         tls,
+		retry:       retry,
+		requestRate: requestRate,
+		maxInFlight: maxInFlight,
+	}, logging.IsDebugOrHigher())
+	if err != nil {
+		return nil, diag.FromErr(err)
 	}
 
-	return client.NewClient(server_url, namespace, key), nil
+	return &providerMeta{
+		client:    client.NewClient(server_url, namespace, key),
+		namespace: namespace,
+		serverURL: server_url,
+		key:       key,
+	}, nil
 }
@@ -4,28 +4,44 @@ import (
 	"os"
 	"testing"
 
-	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
-	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
-var testAccProviders map[string]terraform.ResourceProvider
+var testAccProviders map[string]*schema.Provider
 var testAccProvider *schema.Provider
 
+// testAccMockServer is the fake Shaken Fist API the acceptance tests run
+// against when SHAKENFIST_MOCK is set, otherwise nil.
+var testAccMockServer *mockServer
+
 func init() {
-	testAccProvider = Provider().(*schema.Provider)
-	testAccProviders = map[string]terraform.ResourceProvider{
+	testAccProvider = Provider()
+	testAccProviders = map[string]*schema.Provider{
 		"shakenfist": testAccProvider,
 	}
 }
 
+func TestMain(m *testing.M) {
+	if os.Getenv("SHAKENFIST_MOCK") != "" {
+		testAccMockServer = newMockServer("mockkey")
+		os.Setenv("SHAKENFIST_API_URL", testAccMockServer.URL)
+		os.Setenv("SHAKENFIST_NAMESPACE", "system")
+		os.Setenv("SHAKENFIST_KEY", "mockkey")
+	}
+
+	// Runs the sweepers instead of the tests if -sweep is set
+	resource.TestMain(m)
+}
+
 func TestUnitProvider(t *testing.T) {
-	if err := Provider().(*schema.Provider).InternalValidate(); err != nil {
+	if err := Provider().InternalValidate(); err != nil {
 		t.Fatalf("Error creating Provider: %s", err)
 	}
 }
 
 func TestProvider_impl(t *testing.T) {
-	var _ terraform.ResourceProvider = Provider()
+	var _ *schema.Provider = Provider()
 }
 
 func testAccPreCheck(t *testing.T) {
@@ -41,3 +57,12 @@ func testAccPreCheck(t *testing.T) {
 		}
 	}
 }
+
+// testAccPreCheckMock skips tests that inject failures into the fake Shaken
+// Fist API when the acceptance tests run against a cluster.
+func testAccPreCheckMock(t *testing.T) {
+	if testAccMockServer == nil {
+		t.Skip("SHAKENFIST_MOCK must be set for this acceptance test")
+	}
+	testAccPreCheck(t)
+}
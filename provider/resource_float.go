@@ -3,9 +3,12 @@
 package provider
 
 import (
+	"context"
 	"fmt"
+	"log"
 
-	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	client "github.com/shakenfist/client-go"
 )
 
@@ -19,6 +22,14 @@ func resourceFloat() *schema.Resource {
 				Description: "UUID of Interface",
 				ForceNew:    true,
 			},
+			"namespace": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+				Description: "Namespace of the instance of the interface, " +
+					"by default the provider namespace",
+			},
 			"ipv4": {
 				Type:        schema.TypeString,
 				Optional:    true,
@@ -26,77 +37,125 @@ func resourceFloat() *schema.Resource {
 				Description: "IPv4 Address",
 			},
 		},
-		Create: resourceCreateFloat,
-		Read:   resourceReadFloat,
-		Delete: resourceDeleteFloat,
-		Exists: resourceExistsFloat,
+		CreateContext: resourceCreateFloat,
+		ReadContext:   resourceReadFloat,
+		DeleteContext: resourceDeleteFloat,
 		Importer: &schema.ResourceImporter{
-			State: schema.ImportStatePassthrough,
+			StateContext: schema.ImportStatePassthroughContext,
 		},
 	}
 }
 
-func resourceCreateFloat(d *schema.ResourceData, m interface{}) error {
-	apiClient := m.(*client.Client)
+func resourceCreateFloat(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
 
 	uuid := d.Get("interface").(string)
 
-	err := apiClient.FloatInterface(uuid)
+	namespace, err := m.(*providerMeta).objectNamespace(d)
 	if err != nil {
-		return fmt.Errorf("Unable to float interface: %v", err)
+		return diag.Errorf("Unable to float interface: %v", err)
+	}
+	if namespace != "" {
+		iface, err := apiClient.GetInterface(uuid)
+		if err != nil {
+			return diag.Errorf("Unable to retrieve interface: %v", err)
+		}
+		actual, err := instanceNamespace(apiClient, iface.InstanceUUID)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if actual != namespace {
+			return diag.Errorf("Interface %s is in namespace %s, not %s",
+				uuid, actual, namespace)
+		}
 	}
 
-	d.SetId(uuid)
-
-	if err := resourceReadFloat(d, m); err != nil {
-		return fmt.Errorf("CreateFloat: %v", err)
+	err = apiClient.FloatInterface(uuid)
+	if err != nil {
+		return diag.Errorf("Unable to float interface: %v", err)
 	}
 
-	return nil
+	d.SetId(uuid)
+
+	return resourceReadFloat(ctx, d, m)
 }
 
-func resourceReadFloat(d *schema.ResourceData, m interface{}) error {
-	apiClient := m.(*client.Client)
+func resourceReadFloat(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
 
 	iface, err := apiClient.GetInterface(d.Id())
 	if err != nil {
-		return fmt.Errorf("Unable to retrieve network: %v", err)
+		if isNotFound(err) {
+			log.Printf("[WARN] Interface %s not found, removing from state",
+				d.Id())
+			d.SetId("")
+			return nil
+		}
+		return diag.Errorf("Unable to retrieve interface: %v", err)
 	}
 
 	if iface.Floating == "" {
-		return fmt.Errorf("Interface does not have a floating IP")
+		log.Printf("[WARN] Interface %s does not have a floating IP, "+
+			"removing from state", d.Id())
+		d.SetId("")
+		return nil
 	}
 
 	if err := d.Set("ipv4", iface.Floating); err != nil {
-		return fmt.Errorf("Float IPv4 cannot be set: %v", err)
+		return diag.Errorf("Float IPv4 cannot be set: %v", err)
+	}
+
+	// The interface takes the namespace of its instance
+	inst, err := apiClient.GetInstance(iface.InstanceUUID)
+	if err != nil {
+		if isNotFound(err) {
+			log.Printf("[WARN] Instance %s of interface %s not found, "+
+				"removing from state", iface.InstanceUUID, d.Id())
+			d.SetId("")
+			return nil
+		}
+		return diag.Errorf("Unable to retrieve instance of interface: %v",
+			err)
+	}
+	if inst.State == "deleted" {
+		log.Printf("[WARN] Instance %s of interface %s is deleted, "+
+			"removing from state", iface.InstanceUUID, d.Id())
+		d.SetId("")
+		return nil
+	}
+	if err := d.Set("namespace", inst.Namespace); err != nil {
+		return diag.Errorf("Float namespace cannot be set: %v", err)
 	}
 
 	return nil
 }
 
-func resourceDeleteFloat(d *schema.ResourceData, m interface{}) error {
-	apiClient := m.(*client.Client)
+// instanceNamespace returns the namespace of the instance, used for the
+// namespace of its interfaces.
+func instanceNamespace(apiClient *client.Client, uuid string) (string,
+	error) {
 
-	err := apiClient.DefloatInterface(d.Id())
+	inst, err := apiClient.GetInstance(uuid)
 	if err != nil {
-		return fmt.Errorf("Unable to delete network interface: %v", err)
+		return "", fmt.Errorf("Unable to retrieve instance of interface: %v",
+			err)
 	}
-	d.SetId("")
-	return nil
+	return inst.Namespace, nil
 }
 
-func resourceExistsFloat(d *schema.ResourceData, m interface{}) (bool, error) {
-	apiClient := m.(*client.Client)
+func resourceDeleteFloat(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
 
-	iface, err := apiClient.GetInterface(d.Id())
-	if err != nil {
-		// The interface does not exist, therefore the floating IP does not exist.
-		return false, nil
-	}
+	apiClient := m.(*providerMeta).client
 
-	if iface.Floating == "" {
-		return false, nil
+	err := apiClient.DefloatInterface(d.Id())
+	if err != nil && !isNotFound(err) {
+		return diag.Errorf("Unable to remove floating IP: %v", err)
 	}
-
-	return true, nil
+	d.SetId("")
+	return nil
 }
@@ -5,8 +5,8 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/hashicorp/terraform-plugin-sdk/helper/acctest"
-	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 )
 
 func TestAccShakenFistInstanceNet(t *testing.T) {
@@ -74,3 +74,72 @@ func testAccResourceFloat(randomName string) string {
 	r := strings.NewReplacer("{name}", randomName)
 	return r.Replace(res)
 }
+
+// TestAccShakenFistNamespaceObjects tests creating a network, instance and
+// floating IP in a namespace other than the provider namespace.
+//
+// *** NOTE: Only tested when environment namespace set to 'system'.
+func TestAccShakenFistNamespaceObjects(t *testing.T) {
+	randomName := acctest.RandStringFromCharSet(10, acctest.CharSetAlphaNum)
+
+	namespace := "testacc-" + randomName
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				SkipFunc: testAccSystemKey,
+				Config:   testAccResourceNamespaceObjects(randomName),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"shakenfist_network.external", "namespace",
+						namespace),
+					resource.TestCheckResourceAttr(
+						"shakenfist_instance.jump", "namespace", namespace),
+					resource.TestCheckResourceAttr(
+						"shakenfist_float.jump", "namespace", namespace),
+				),
+			},
+		},
+	})
+}
+
+func testAccResourceNamespaceObjects(randomName string) string {
+	res := `
+	resource "shakenfist_namespace" "lab" {
+		name = "testacc-{name}"
+	}
+
+	resource "shakenfist_instance" "jump" {
+		name = "testacc-{name}-jump"
+		namespace = shakenfist_namespace.lab.name
+		cpus = 1
+		memory = 1024
+		disk {
+			size = 8
+			base = "cirros"
+			bus = "ide"
+			type = "disk"
+		}
+		network {
+			network_uuid = shakenfist_network.external.id
+		}
+	}
+
+	resource "shakenfist_network" "external" {
+		name = "testacc-{name}-external"
+		namespace = shakenfist_namespace.lab.name
+		netblock = "10.0.1.0/24"
+		provide_dhcp = true
+		provide_nat = false
+	}
+
+	resource "shakenfist_float" "jump" {
+		interface = shakenfist_instance.jump.network[0].interface_uuid
+		namespace = shakenfist_namespace.lab.name
+	}`
+
+	r := strings.NewReplacer("{name}", randomName)
+	return r.Replace(res)
+}
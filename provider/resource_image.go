@@ -0,0 +1,241 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	client "github.com/shakenfist/client-go"
+)
+
+func resourceImage() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"url": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "URL of the image to cache",
+			},
+			"nodes": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				ForceNew: true,
+				Description: "Nodes to cache the image on, " +
+					"by default the node chosen by Shaken Fist",
+				Elem: &schema.Schema{
+					Type: schema.TypeString},
+			},
+			"checksum": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Checksum of the cached image",
+			},
+			"size": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Size of the cached image in bytes",
+			},
+			"cached_nodes": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Nodes the image is cached on",
+				Elem: &schema.Schema{
+					Type: schema.TypeString},
+			},
+		},
+		CreateContext: resourceCreateImage,
+		ReadContext:   resourceReadImage,
+		DeleteContext: resourceDeleteImage,
+		Importer: &schema.ResourceImporter{
+			StateContext: schema.ImportStatePassthroughContext,
+		},
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(30 * time.Minute),
+		},
+	}
+}
+
+func resourceCreateImage(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
+
+	url := d.Get("url").(string)
+	nodes := expandStringSet(d.Get("nodes").(*schema.Set))
+
+	if len(nodes) == 0 {
+		if err := apiClient.CacheImage(url); err != nil {
+			return diag.Errorf("Unable to cache image: %v", err)
+		}
+	}
+	for _, node := range nodes {
+		nodeClient, err := m.(*providerMeta).createClient(
+			createOptions{node: node})
+		if err != nil {
+			return diag.Errorf("Unable to cache image on node %s: %v",
+				node, err)
+		}
+		if err := nodeClient.CacheImage(url); err != nil {
+			return diag.Errorf("Unable to cache image on node %s: %v",
+				node, err)
+		}
+	}
+
+	d.SetId(url)
+
+	err := resource.RetryContext(ctx, d.Timeout(schema.TimeoutCreate),
+		func() *resource.RetryError {
+
+			images, err := apiClient.GetImages("")
+			if err != nil {
+				return retryError(err, "Unable to retrieve images")
+			}
+
+			done, err := imageCached(images, url, nodes)
+			if err != nil {
+				return resource.NonRetryableError(err)
+			}
+			if !done {
+				return resource.RetryableError(fmt.Errorf(
+					"image not cached"))
+			}
+
+			return nil
+		},
+	)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceReadImage(ctx, d, m)
+}
+
+func resourceReadImage(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
+
+	images, err := apiClient.GetImages("")
+	if err != nil {
+		return diag.Errorf("Unable to retrieve images: %v", err)
+	}
+
+	cached, _, _ := cachedImageNodes(images, d.Id())
+	if len(cached) == 0 {
+		log.Printf("[WARN] Image %s is not cached, removing from state",
+			d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	nodes := expandStringSet(d.Get("nodes").(*schema.Set))
+	for _, node := range nodes {
+		if !containsString(cached, node) {
+			log.Printf("[WARN] Image %s is not cached on node %s, "+
+				"removing from state", d.Id(), node)
+			d.SetId("")
+			return nil
+		}
+	}
+
+	for _, img := range images {
+		if img.URL == d.Id() && img.State == "created" {
+			if err := d.Set("checksum", img.Checksum); err != nil {
+				return diag.Errorf("Image checksum cannot be set: %v", err)
+			}
+			if err := d.Set("size", int(img.Size)); err != nil {
+				return diag.Errorf("Image size cannot be set: %v", err)
+			}
+			break
+		}
+	}
+
+	if err := d.Set("url", d.Id()); err != nil {
+		return diag.Errorf("Image URL cannot be set: %v", err)
+	}
+	if err := d.Set("cached_nodes", cached); err != nil {
+		return diag.Errorf("Image cached nodes cannot be set: %v", err)
+	}
+
+	return nil
+}
+
+// Shaken Fist has no API to remove an image from the cache, so deleting the
+// resource only removes it from the Terraform state.
+func resourceDeleteImage(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	log.Printf("[INFO] Image %s remains in the Shaken Fist image cache",
+		d.Id())
+	d.SetId("")
+	return nil
+}
+
+// imageCached reports whether the image URL has been cached as requested. With
+// explicit nodes it must be cached on each of them and fails as soon as one of
+// their downloads fails. Otherwise a single cached copy is enough, and failed
+// downloads only matter once no other download is still pending.
+func imageCached(images []client.Image, url string, nodes []string) (bool,
+	error) {
+
+	cached, failed, pending := cachedImageNodes(images, url)
+
+	if len(nodes) > 0 {
+		for _, node := range failed {
+			if containsString(nodes, node) {
+				return false, fmt.Errorf(
+					"image download failed on node %s", node)
+			}
+		}
+		for _, node := range nodes {
+			if !containsString(cached, node) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+
+	if len(cached) > 0 {
+		return true, nil
+	}
+	if len(failed) > 0 && len(pending) == 0 {
+		return false, fmt.Errorf("image download failed on nodes %s",
+			strings.Join(failed, ", "))
+	}
+	return false, nil
+}
+
+// cachedImageNodes returns the sorted nodes the image URL has been downloaded
+// to, the nodes where the download failed, and the nodes where it is still in
+// progress.
+func cachedImageNodes(images []client.Image, url string) (cached,
+	failed, pending []string) {
+
+	for _, img := range images {
+		if img.URL != url {
+			continue
+		}
+
+		switch img.State {
+		case "created":
+			cached = append(cached, img.Node)
+		case "error":
+			failed = append(failed, img.Node)
+		case "deleted":
+		default:
+			pending = append(pending, img.Node)
+		}
+	}
+
+	sort.Strings(cached)
+	sort.Strings(failed)
+	sort.Strings(pending)
+	return cached, failed, pending
+}
@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	client "github.com/shakenfist/client-go"
+)
+
+func TestAccShakenFistImage(t *testing.T) {
+	resName := "shakenfist_image.cirros"
+	dataName := "data.shakenfist_images.cirros"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceImage(),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						resName, "url", testAccImageURL),
+					resource.TestCheckResourceAttrSet(resName, "checksum"),
+					resource.TestCheckResourceAttrSet(resName, "size"),
+					resource.TestCheckResourceAttrPair(
+						resName, "cached_nodes.0",
+						"data.shakenfist_nodes.all", "nodes.0.name"),
+
+					resource.TestCheckResourceAttrPair(
+						dataName, "images.0.node",
+						"data.shakenfist_nodes.all", "nodes.0.name"),
+					resource.TestCheckResourceAttr(
+						dataName, "images.0.url", testAccImageURL),
+					resource.TestCheckResourceAttr(
+						dataName, "images.0.state", "created"),
+				),
+			},
+		},
+	})
+}
+
+const testAccImageURL = "https://download.cirros-cloud.net/0.5.2/" +
+	"cirros-0.5.2-x86_64-disk.img"
+
+func testAccResourceImage() string {
+	res := `
+	data "shakenfist_nodes" "all" {
+	}
+
+	resource "shakenfist_image" "cirros" {
+		url = "{url}"
+		nodes = [data.shakenfist_nodes.all.nodes[0].name]
+	}
+
+	data "shakenfist_images" "cirros" {
+		url = shakenfist_image.cirros.url
+		node = data.shakenfist_nodes.all.nodes[0].name
+	}`
+
+	r := strings.NewReplacer("{url}", testAccImageURL)
+	return r.Replace(res)
+}
+
+func TestUnitCachedImageNodes(t *testing.T) {
+	images := []client.Image{
+		{URL: "http://a/img", Node: "sf-2", State: "created"},
+		{URL: "http://a/img", Node: "sf-1", State: "created"},
+		{URL: "http://a/img", Node: "sf-3", State: "error"},
+		{URL: "http://a/img", Node: "sf-4", State: "creating"},
+		{URL: "http://b/img", Node: "sf-3", State: "created"},
+	}
+
+	cached, failed, pending := cachedImageNodes(images, "http://a/img")
+	if strings.Join(cached, ",") != "sf-1,sf-2" {
+		t.Errorf("Incorrect cached nodes: %v", cached)
+	}
+	if strings.Join(failed, ",") != "sf-3" {
+		t.Errorf("Incorrect failed nodes: %v", failed)
+	}
+	if strings.Join(pending, ",") != "sf-4" {
+		t.Errorf("Incorrect pending nodes: %v", pending)
+	}
+}
+
+func TestUnitImageCached(t *testing.T) {
+	url := "http://a/img"
+	tests := []struct {
+		name   string
+		images []client.Image
+		nodes  []string
+		done   bool
+		fail   bool
+	}{
+		{"nothing yet", nil, nil, false, false},
+		{"cached despite stale error", []client.Image{
+			{URL: url, Node: "sf-1", State: "error"},
+			{URL: url, Node: "sf-2", State: "created"},
+		}, nil, true, false},
+		{"error while pending", []client.Image{
+			{URL: url, Node: "sf-1", State: "error"},
+			{URL: url, Node: "sf-2", State: "downloading"},
+		}, nil, false, false},
+		{"error only", []client.Image{
+			{URL: url, Node: "sf-1", State: "error"},
+			{URL: url, Node: "sf-2", State: "deleted"},
+		}, nil, false, true},
+		{"error on other node", []client.Image{
+			{URL: url, Node: "sf-1", State: "error"},
+			{URL: url, Node: "sf-2", State: "created"},
+		}, []string{"sf-2"}, true, false},
+		{"error on requested node", []client.Image{
+			{URL: url, Node: "sf-1", State: "error"},
+			{URL: url, Node: "sf-2", State: "created"},
+		}, []string{"sf-1", "sf-2"}, false, true},
+		{"requested node pending", []client.Image{
+			{URL: url, Node: "sf-1", State: "downloading"},
+			{URL: url, Node: "sf-2", State: "created"},
+		}, []string{"sf-1", "sf-2"}, false, false},
+	}
+
+	for _, test := range tests {
+		done, err := imageCached(test.images, url, test.nodes)
+		if done != test.done || (err != nil) != test.fail {
+			t.Errorf("%s: got done %v, error %v", test.name, done, err)
+		}
+	}
+}
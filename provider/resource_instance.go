@@ -3,14 +3,19 @@
 package provider
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"regexp"
 	"sort"
-	"strings"
+	"sync"
 	"time"
 
-	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
-	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	client "github.com/shakenfist/client-go"
 )
 
@@ -24,6 +29,14 @@ func resourceInstance() *schema.Resource {
 				Description: "The name of the instance",
 				ForceNew:    true,
 			},
+			"namespace": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+				Description: "Namespace of the instance, " +
+					"by default the provider namespace",
+			},
 			"uuid": {
 				Type:        schema.TypeString,
 				Computed:    true,
@@ -113,7 +126,7 @@ func resourceInstance() *schema.Resource {
 							Computed: true,
 							Description: "The " +
 								"IPv4 address of the network interface",
-							ValidateFunc: validateIPAddr,
+							ValidateDiagFunc: validateIPAddr,
 						},
 						"mac": {
 							Type:     schema.TypeString,
@@ -122,7 +135,7 @@ func resourceInstance() *schema.Resource {
 							Computed: true,
 							Description: "The " +
 								"MAC address of the network interface",
-							ValidateFunc: validateMAC,
+							ValidateDiagFunc: validateMAC,
 						},
 						"model": {
 							Type:        schema.TypeString,
@@ -156,6 +169,36 @@ func resourceInstance() *schema.Resource {
 				Computed:    true,
 				Description: "Shaken Fist node running this instance",
 			},
+			"placement": {
+				Type:     schema.TypeList,
+				Optional: true,
+				ForceNew: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"node": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							ForceNew:    true,
+							Description: "Shaken Fist node to run the instance on",
+						},
+						"affinity_group": {
+							Type:     schema.TypeString,
+							Optional: true,
+							ForceNew: true,
+							Description: "Instances in the same " +
+								"affinity group run on the same node",
+						},
+						"anti_affinity_group": {
+							Type:     schema.TypeString,
+							Optional: true,
+							ForceNew: true,
+							Description: "Instances in the same " +
+								"anti-affinity group run on different nodes",
+						},
+					},
+				},
+			},
 			"user_data": {
 				Type:     schema.TypeString,
 				Optional: true,
@@ -166,6 +209,8 @@ func resourceInstance() *schema.Resource {
 			"metadata": {
 				Type:     schema.TypeMap,
 				Optional: true,
+				Description: "Metadata of the instance, " +
+					"other keys are removed",
 				Elem: &schema.Schema{
 					Type: schema.TypeString},
 			},
@@ -189,69 +234,108 @@ func resourceInstance() *schema.Resource {
 				Computed:    true,
 				Description: "Power state of the instance",
 			},
+			"desired_power_state": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Description: "Power state to maintain " +
+					"the instance in: on, off or paused",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(
+					[]string{"on", "off", "paused"}, false)),
+			},
+			"reboot_triggers": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				Description: "Arbitrary values " +
+					"that reboot the instance when changed",
+				Elem: &schema.Schema{
+					Type: schema.TypeString},
+			},
+			"reboot_type": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "soft",
+				Description: "Type of reboot " +
+					"performed when reboot_triggers change: soft or hard",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(
+					[]string{"soft", "hard"}, false)),
+			},
 		},
-		Create: resourceCreateInstance,
-		Read:   resourceReadInstance,
-		Delete: resourceDeleteInstance,
-		Exists: resourceExistsInstance,
-		Update: resourceUpdateInstance,
+		CustomizeDiff: resourceInstanceCustomizeDiff,
+		CreateContext: resourceCreateInstance,
+		ReadContext:   resourceReadInstance,
+		DeleteContext: resourceDeleteInstance,
+		UpdateContext: resourceUpdateInstance,
 		Importer: &schema.ResourceImporter{
-			State: schema.ImportStatePassthrough,
+			StateContext: schema.ImportStatePassthroughContext,
 		},
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
 			Delete: schema.DefaultTimeout(3 * time.Minute),
 		},
 	}
 }
 
-func validateIPAddr(v interface{}, k string) ([]string, []error) {
-	var errs []error
-	var warns []string
-
+func validateIPAddr(v interface{}, path cty.Path) diag.Diagnostics {
 	value, ok := v.(string)
 	if !ok {
-		errs = append(errs, fmt.Errorf("Expected IP address to be a string"))
-		return warns, errs
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "Expected IP address to be a string",
+			AttributePath: path,
+		}}
 	}
 
-	netblock := regexp.MustCompile(
+	re := regexp.MustCompile(
 		`^[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}$`)
-	if !netblock.Match([]byte(value)) {
-		errs = append(errs,
-			fmt.Errorf("Netblock must be IPv4 address. Got %s", value))
-		return warns, errs
+	if !re.Match([]byte(value)) {
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "IP address must be an IPv4 address",
+			Detail:        fmt.Sprintf("Got %s", value),
+			AttributePath: path,
+		}}
 	}
-	return warns, errs
-}
 
-func validateMAC(v interface{}, k string) ([]string, []error) {
-	var errs []error
-	var warns []string
+	return nil
+}
 
+func validateMAC(v interface{}, path cty.Path) diag.Diagnostics {
 	value, ok := v.(string)
 	if !ok {
-		errs = append(errs, fmt.Errorf("Expected MAC address to be a string"))
-		return warns, errs
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "Expected MAC address to be a string",
+			AttributePath: path,
+		}}
 	}
 
-	netblock := regexp.MustCompile(
+	re := regexp.MustCompile(
 		`^[0-9a-fA-F]{2}\:[0-9a-fA-F]{2}\:[0-9a-fA-F]{2}\:` +
 			`[0-9a-fA-F]{2}\:[0-9a-fA-F]{2}\:[0-9a-fA-F]{2}$`)
-	if !netblock.Match([]byte(value)) {
-		errs = append(errs,
-			fmt.Errorf("MAC address must be a valid MAC. Got %s", value))
-		return warns, errs
+	if !re.Match([]byte(value)) {
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "MAC address must be a valid MAC",
+			Detail:        fmt.Sprintf("Got %s", value),
+			AttributePath: path,
+		}}
 	}
 
-	return warns, errs
+	return nil
 }
 
-func resourceCreateInstance(d *schema.ResourceData, m interface{}) error {
-	apiClient := m.(*client.Client)
+func resourceCreateInstance(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
+
+	namespace, err := m.(*providerMeta).objectNamespace(d)
+	if err != nil {
+		return diag.Errorf("Unable to create instance: %v", err)
+	}
 
 	var disks []client.DiskSpec
-	var err error
 
 	for _, d := range d.Get("disk").([]interface{}) {
 		disk := d.(map[string]interface{})
@@ -289,7 +373,7 @@ func resourceCreateInstance(d *schema.ResourceData, m interface{}) error {
 
 	videoConf := d.Get("video").([]interface{})
 	if len(videoConf) > 1 {
-		return fmt.Errorf("Instances only accept one video card ")
+		return diag.Errorf("Instances only accept one video card ")
 	}
 	video := client.VideoSpec{}
 	if len(videoConf) == 0 {
@@ -301,72 +385,172 @@ func resourceCreateInstance(d *schema.ResourceData, m interface{}) error {
 		video.Memory = v["memory"].(int)
 	}
 
-	inst, err := apiClient.CreateInstance(d.Get("name").(string),
+	// The placement groups are recorded in the instance metadata, which is
+	// used to place later instances.
+	placement := expandPlacement(d)
+	node := ""
+	metadata := d.Get("metadata").(map[string]interface{})
+	// The placement lock is held until the instance has been scheduled to a
+	// node, so that later placements see it.
+	unlockPlacement := func() {}
+	if placement != nil {
+		placementLock.Lock()
+		var unlock sync.Once
+		unlockPlacement = func() { unlock.Do(placementLock.Unlock) }
+		defer unlockPlacement()
+
+		node, err = choosePlacementNode(apiClient, placement)
+		if err != nil {
+			return diag.Errorf("Unable to place instance: %v", err)
+		}
+
+		for k := range placement.metadata() {
+			if _, ok := metadata[k]; ok {
+				return diag.Errorf("Metadata key %s is set by the "+
+					"placement block", k)
+			}
+		}
+	}
+
+	createClient, err := m.(*providerMeta).createClient(
+		createOptions{namespace: namespace, node: node})
+	if err != nil {
+		return diag.Errorf("Unable to create instance: %v", err)
+	}
+	inst, err := createClient.CreateInstance(d.Get("name").(string),
 		d.Get("cpus").(int), d.Get("memory").(int), networks, disks, video,
 		d.Get("ssh_key").(string), d.Get("user_data").(string))
 	if err != nil {
-		return fmt.Errorf("Unable to create instance: %v", err)
+		return diag.Errorf("Unable to create instance: %v", err)
 	}
 
 	// If Shaken Fist has a server error, it can return a blank UUID
 	if inst.UUID == "" {
-		return fmt.Errorf("Shaken Fist has returned a null instance UUID")
+		return diag.Errorf("Shaken Fist has returned a null instance UUID")
 	}
 
 	// Signal to TF that the instance exists by setting the ID.
 	if err := d.Set("uuid", inst.UUID); err != nil {
-		return fmt.Errorf("Instance UUID cannot be set: %v", err)
+		return diag.Errorf("Instance UUID cannot be set: %v", err)
 	}
 	d.SetId(inst.UUID)
 
 	// Set metadata on the instance
-	for k, v := range d.Get("metadata").(map[string]interface{}) {
+	if placement != nil {
+		for k, v := range placement.metadata() {
+			err := apiClient.SetMetadata(client.TypeInstance, inst.UUID, k, v)
+			if err != nil {
+				return diag.Errorf(
+					"CreateInstance cannot store placement: %v", err)
+			}
+		}
+	}
+	for k, v := range metadata {
 		val, ok := v.(string)
 		if !ok {
-			return fmt.Errorf("Tag value is not a string")
+			return diag.Errorf("Tag value is not a string")
 		}
 
 		err := apiClient.SetMetadata(client.TypeInstance, inst.UUID, k, val)
 		if err != nil {
-			return fmt.Errorf("CreateInstance cannot store metadata: %v", err)
+			return diag.Errorf("CreateInstance cannot store metadata: %v", err)
 		}
 	}
 
-	return resource.Retry(d.Timeout(schema.TimeoutCreate),
+	var created client.Instance
+	err = resource.RetryContext(ctx, d.Timeout(schema.TimeoutCreate),
 		func() *resource.RetryError {
 
 			i, err := apiClient.GetInstance(d.Id())
 			if err != nil {
-				if strings.Contains(err.Error(), "not found") {
-					return resource.NonRetryableError(nil)
-				} else {
-					return resource.NonRetryableError(fmt.Errorf(
-						"Unable to check instance existence: %v", err))
+				if isNotFound(err) {
+					// The instance may not be visible immediately.
+					return resource.RetryableError(fmt.Errorf(
+						"instance not found"))
 				}
+				return retryError(err, "Unable to check instance existence")
 			}
 
 			if i.State == "error" {
 				return resource.NonRetryableError(fmt.Errorf(
 					"instance in error state"))
 			}
+			if i.Node != "" {
+				unlockPlacement()
+			}
 			if i.State != "created" {
 				return resource.RetryableError(fmt.Errorf(
 					"instance not created"))
 			}
 
-			return resource.NonRetryableError(resourceReadInstance(d, m))
+			created = i
+			return nil
 		},
 	)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if node != "" && created.Node != node {
+		return diag.Errorf("Shaken Fist did not honour the instance "+
+			"placement, the instance was requested on node %s but is "+
+			"running on node %s", node, created.Node)
+	}
+	if namespace == "" {
+		namespace = m.(*providerMeta).namespace
+	}
+	if created.Namespace != namespace {
+		return diag.Errorf("Shaken Fist did not honour the instance "+
+			"namespace, the instance was requested in namespace %s but is "+
+			"in namespace %s", namespace, created.Namespace)
+	}
+
+	if v, ok := d.GetOk("desired_power_state"); ok {
+		err := setInstancePowerState(ctx, apiClient, d.Id(), v.(string),
+			d.Timeout(schema.TimeoutCreate))
+		if err != nil {
+			return diag.Errorf("CreateInstance error: %v", err)
+		}
+	}
+
+	return resourceReadInstance(ctx, d, m)
 }
 
-func resourceReadInstance(d *schema.ResourceData, m interface{}) error {
-	apiClient := m.(*client.Client)
+func resourceReadInstance(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
 
 	inst, err := apiClient.GetInstance(d.Id())
 	if err != nil {
-		return fmt.Errorf("Unable to retrieve instance: %v", err)
+		if isNotFound(err) {
+			log.Printf("[WARN] Instance %s not found, removing from state",
+				d.Id())
+			d.SetId("")
+			return nil
+		}
+		return diag.Errorf("Unable to retrieve instance: %v", err)
 	}
 
+	if inst.State == "deleted" {
+		log.Printf("[WARN] Instance %s is deleted, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	if err := d.Set("namespace", inst.Namespace); err != nil {
+		return diag.Errorf("Instance Namespace cannot be set: %v", err)
+	}
+
+	return diag.FromErr(setInstanceData(d, apiClient, inst))
+}
+
+// setInstanceData sets the Terraform attributes from the Shaken Fist
+// instance, including the network interfaces and metadata. It is shared by
+// the instance resource and data sources.
+func setInstanceData(d *schema.ResourceData, apiClient *client.Client,
+	inst client.Instance) error {
+
 	if err := d.Set("uuid", inst.UUID); err != nil {
 		return fmt.Errorf("Instance UUID cannot be set: %v", err)
 	}
@@ -380,26 +564,10 @@ func resourceReadInstance(d *schema.ResourceData, m interface{}) error {
 		return fmt.Errorf("Instance Memory cannot be set: %v", err)
 	}
 
-	var disks []map[string]interface{}
-	for _, d := range inst.DiskSpecs {
-		disks = append(disks, map[string]interface{}{
-			"size": d.Size,
-			"base": d.Base,
-			"bus":  d.Bus,
-			"type": d.Type,
-		})
-	}
-	if err := d.Set("disk", disks); err != nil {
+	if err := d.Set("disk", flattenDiskSpecs(inst.DiskSpecs)); err != nil {
 		return fmt.Errorf("Instance DiskSpecs cannot be set: %v", err)
 	}
-
-	video := []map[string]interface{}{
-		{
-			"model":  inst.Video.Model,
-			"memory": inst.Video.Memory,
-		},
-	}
-	if err := d.Set("video", video); err != nil {
+	if err := d.Set("video", flattenVideoSpec(inst.Video)); err != nil {
 		return fmt.Errorf("Instance Video cannot be set: %v", err)
 	}
 
@@ -425,17 +593,68 @@ func resourceReadInstance(d *schema.ResourceData, m interface{}) error {
 		return fmt.Errorf("Instance PowerState cannot be set: %v", err)
 	}
 
-	// Retrieve Interface UUID's
-	uuid, err := getInterfaceUUIDs(apiClient, d.Id())
+	networks, err := flattenInstanceNetworks(apiClient, inst.UUID)
 	if err != nil {
 		return fmt.Errorf("ReadInstance error: %v", err)
 	}
+	if err := d.Set("network", networks); err != nil {
+		return fmt.Errorf("Instance networks cannot be set: %v", err)
+	}
+
+	// Retrieve metadata
+	metadata, err := apiClient.GetMetadata(client.TypeInstance, inst.UUID)
+	if err != nil {
+		return fmt.Errorf("ReadInstance unable to retrieve metadata: %v", err)
+	}
+	if err := d.Set("metadata", withoutPlacementMetadata(metadata)); err != nil {
+		return fmt.Errorf("Instance Metadata cannot be set: %v", err)
+	}
+
+	return nil
+}
+
+// flattenDiskSpecs converts the instance disks to Terraform disk blocks.
+func flattenDiskSpecs(diskSpecs []client.DiskSpec) []map[string]interface{} {
+	var disks []map[string]interface{}
+	for _, d := range diskSpecs {
+		disks = append(disks, map[string]interface{}{
+			"size": d.Size,
+			"base": d.Base,
+			"bus":  d.Bus,
+			"type": d.Type,
+		})
+	}
+
+	return disks
+}
+
+// flattenVideoSpec converts the instance video card to a Terraform video
+// block.
+func flattenVideoSpec(video client.VideoSpec) []map[string]interface{} {
+	return []map[string]interface{}{
+		{
+			"model":  video.Model,
+			"memory": video.Memory,
+		},
+	}
+}
+
+// flattenInstanceNetworks retrieves the instance interfaces and converts them
+// to Terraform network blocks in the Shaken Fist interface order.
+func flattenInstanceNetworks(apiClient *client.Client,
+	instanceUUID string) ([]map[string]interface{}, error) {
+
+	// Retrieve Interface UUID's
+	uuid, err := getInterfaceUUIDs(apiClient, instanceUUID)
+	if err != nil {
+		return nil, err
+	}
 
 	var networks []map[string]interface{}
 	for _, u := range uuid {
 		n, err := apiClient.GetInterface(u)
 		if err != nil {
-			return fmt.Errorf("Cannot retrieve interface: %v", err)
+			return nil, fmt.Errorf("Cannot retrieve interface: %v", err)
 		}
 
 		networks = append(networks, map[string]interface{}{
@@ -448,41 +667,33 @@ func resourceReadInstance(d *schema.ResourceData, m interface{}) error {
 		})
 	}
 
-	if err := d.Set("network", networks); err != nil {
-		return fmt.Errorf("Instance networks cannot be set: %v", err)
-	}
-
-	// Retrieve metadata
-	metadata, err := apiClient.GetMetadata(client.TypeInstance, inst.UUID)
-	if err != nil {
-		return fmt.Errorf("ReadInstance unable to retrieve metadata: %v", err)
-	}
-	if err := d.Set("metadata", metadata); err != nil {
-		return fmt.Errorf("Instance Metadata cannot be set: %v", err)
-	}
-
-	return nil
+	return networks, nil
 }
 
-func resourceDeleteInstance(d *schema.ResourceData, m interface{}) error {
-	apiClient := m.(*client.Client)
+func resourceDeleteInstance(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
 
 	err := apiClient.DeleteInstance(d.Id())
 	if err != nil {
-		return fmt.Errorf("Unable to retrieve network: %v", err)
+		if isNotFound(err) {
+			d.SetId("")
+			return nil
+		}
+		return diag.Errorf("Unable to delete instance: %v", err)
 	}
 
-	return resource.Retry(d.Timeout(schema.TimeoutDelete),
+	err = resource.RetryContext(ctx, d.Timeout(schema.TimeoutDelete),
 		func() *resource.RetryError {
 
 			i, err := apiClient.GetInstance(d.Id())
 			if err != nil {
-				if strings.Contains(err.Error(), "not found") {
-					return resource.NonRetryableError(nil)
-				} else {
-					return resource.NonRetryableError(fmt.Errorf(
-						"Unable to check instance existence: %v", err))
+				if isNotFound(err) {
+					d.SetId("")
+					return nil
 				}
+				return retryError(err, "Unable to check instance existence")
 			}
 
 			if i.State == "error" {
@@ -496,40 +707,189 @@ func resourceDeleteInstance(d *schema.ResourceData, m interface{}) error {
 
 			d.SetId("")
 
-			return resource.NonRetryableError(nil)
+			return nil
 		},
 	)
+
+	return diag.FromErr(err)
+}
+
+func resourceUpdateInstance(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
+
+	if d.HasChange("metadata") {
+		if err := updateMetadata(client.TypeInstance, d, apiClient); err != nil {
+			return diag.Errorf("UpdateInstance error: %v", err)
+		}
+	}
+
+	if v, ok := d.GetOk("desired_power_state"); ok &&
+		d.HasChanges("desired_power_state", "power_state") {
+
+		err := setInstancePowerState(ctx, apiClient, d.Id(), v.(string),
+			d.Timeout(schema.TimeoutUpdate))
+		if err != nil {
+			return diag.Errorf("UpdateInstance error: %v", err)
+		}
+	}
+
+	// The reboot follows any power state change, so that an instance powered
+	// on by the same apply can be rebooted
+	if d.HasChange("reboot_triggers") {
+		err := rebootInstance(ctx, apiClient, d.Id(),
+			d.Get("reboot_type").(string) == "hard",
+			d.Timeout(schema.TimeoutUpdate))
+		if err != nil {
+			// The changed triggers are applied by the next reboot
+			old, _ := d.GetChange("reboot_triggers")
+			if err := d.Set("reboot_triggers", old); err != nil {
+				log.Printf("[WARN] Instance reboot triggers cannot be "+
+					"restored: %v", err)
+			}
+			return diag.Errorf("UpdateInstance error: %v", err)
+		}
+	}
+
+	return resourceReadInstance(ctx, d, m)
+}
+
+// resourceInstanceCustomizeDiff plans an update when the power state of the
+// instance has drifted from the desired power state, such as an instance
+// powered off outside Terraform.
+func resourceInstanceCustomizeDiff(ctx context.Context, d *schema.ResourceDiff,
+	m interface{}) error {
+
+	desired := d.Get("desired_power_state").(string)
+	if d.Id() == "" || desired == "" {
+		return nil
+	}
+
+	if d.Get("power_state").(string) != desired {
+		return d.SetNew("power_state", desired)
+	}
+	return nil
 }
 
-func resourceExistsInstance(d *schema.ResourceData, m interface{}) (bool, error) {
-	apiClient := m.(*client.Client)
+// setInstancePowerState requests the power state change required to move the
+// instance to the desired power state, then waits for Shaken Fist to report
+// that power state.
+//
+// Shaken Fist cannot pause an instance that is powered off, therefore the
+// instance is powered on first.
+func setInstancePowerState(ctx context.Context, apiClient *client.Client,
+	uuid, desired string, timeout time.Duration) error {
 
-	i, err := apiClient.GetInstance(d.Id())
+	inst, err := apiClient.GetInstance(uuid)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			return false, nil
+		return fmt.Errorf("Unable to retrieve instance: %v", err)
+	}
+
+	if inst.PowerState == desired {
+		return nil
+	}
+
+	switch desired {
+	case "on":
+		if inst.PowerState == "paused" {
+			err = apiClient.UnpauseInstance(uuid)
 		} else {
-			return false, fmt.Errorf("Unable to check instance existence: %v", err)
+			err = apiClient.PowerOnInstance(uuid)
 		}
+
+	case "off":
+		err = apiClient.PowerOffInstance(uuid)
+
+	case "paused":
+		if inst.PowerState != "on" {
+			err = setInstancePowerState(ctx, apiClient, uuid, "on", timeout)
+			if err != nil {
+				return err
+			}
+		}
+		err = apiClient.PauseInstance(uuid)
+
+	default:
+		return fmt.Errorf("Unknown power state: %s", desired)
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to change power state to %s: %v",
+			desired, err)
 	}
 
-	if i.State == "deleted" {
-		return false, nil
+	return waitInstancePowerState(ctx, apiClient, uuid, desired, timeout)
+}
+
+// rebootInstance reboots the instance and waits for Shaken Fist to report
+// that its power state has left on and returned to on. Only an instance that
+// is powered on can be rebooted.
+func rebootInstance(ctx context.Context, apiClient *client.Client,
+	uuid string, hard bool, timeout time.Duration) error {
+
+	inst, err := apiClient.GetInstance(uuid)
+	if err != nil {
+		return fmt.Errorf("Unable to retrieve instance: %v", err)
+	}
+
+	if inst.PowerState != "on" {
+		return fmt.Errorf("Instance %s cannot be rebooted, its power state "+
+			"is %s", uuid, inst.PowerState)
 	}
 
-	return true, nil
+	if err := apiClient.RebootInstance(uuid, hard); err != nil {
+		return fmt.Errorf("Unable to reboot instance: %v", err)
+	}
+
+	left := false
+	return resource.RetryContext(ctx, timeout, func() *resource.RetryError {
+		i, err := apiClient.GetInstance(uuid)
+		if err != nil {
+			return retryError(err, "Unable to check instance power state")
+		}
+		if i.State == "error" {
+			return resource.NonRetryableError(fmt.Errorf(
+				"instance in error state"))
+		}
+
+		if i.PowerState != "on" {
+			left = true
+			return resource.RetryableError(fmt.Errorf(
+				"instance power state is %s, waiting for on",
+				i.PowerState))
+		}
+		if !left {
+			return resource.RetryableError(fmt.Errorf(
+				"instance power state has not left on"))
+		}
+
+		return nil
+	})
 }
 
-func resourceUpdateInstance(d *schema.ResourceData, m interface{}) error {
-	apiClient := m.(*client.Client)
+// waitInstancePowerState waits for Shaken Fist to report the instance is in
+// the required power state.
+func waitInstancePowerState(ctx context.Context, apiClient *client.Client,
+	uuid, powerState string, timeout time.Duration) error {
 
-	if d.HasChange("metadata") {
-		if err := updateMetadata(client.TypeInstance, d, apiClient); err != nil {
-			return fmt.Errorf("UpdateInstance error: %v", err)
+	return resource.RetryContext(ctx, timeout, func() *resource.RetryError {
+		i, err := apiClient.GetInstance(uuid)
+		if err != nil {
+			return retryError(err, "Unable to check instance power state")
 		}
-	}
 
-	return nil
+		if i.State == "error" {
+			return resource.NonRetryableError(fmt.Errorf(
+				"instance in error state"))
+		}
+		if i.PowerState != powerState {
+			return resource.RetryableError(fmt.Errorf(
+				"instance power state is %s, waiting for %s",
+				i.PowerState, powerState))
+		}
+
+		return nil
+	})
 }
 
 // getInterfaceUUIDS returns a list of network UUID's as connected to the
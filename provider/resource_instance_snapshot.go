@@ -0,0 +1,248 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	client "github.com/shakenfist/client-go"
+)
+
+func resourceInstanceSnapshot() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"instance_uuid": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The UUID of the instance to snapshot",
+			},
+			"devices": {
+				Type:     schema.TypeList,
+				Optional: true,
+				ForceNew: true,
+				Description: "Disk devices to snapshot, such as vda, " +
+					"by default all disks",
+				Elem: &schema.Schema{
+					Type: schema.TypeString},
+			},
+			"keepers": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				ForceNew: true,
+				Description: "Arbitrary values " +
+					"that take a new snapshot when changed",
+				Elem: &schema.Schema{
+					Type: schema.TypeString},
+			},
+			"snapshots": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The snapshot of each disk",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"device": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The disk device",
+						},
+						"uuid": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The UUID of the snapshot",
+						},
+						"url": {
+							Type:     schema.TypeString,
+							Computed: true,
+							Description: "URL of the snapshot, " +
+								"for use as an instance disk base",
+						},
+					},
+				},
+			},
+		},
+		CreateContext: resourceCreateInstanceSnapshot,
+		ReadContext:   resourceReadInstanceSnapshot,
+		DeleteContext: resourceDeleteInstanceSnapshot,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(30 * time.Minute),
+		},
+	}
+}
+
+func resourceCreateInstanceSnapshot(ctx context.Context,
+	d *schema.ResourceData, m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
+
+	instanceUUID := d.Get("instance_uuid").(string)
+
+	var devices []string
+	for _, v := range d.Get("devices").([]interface{}) {
+		devices = append(devices, v.(string))
+	}
+
+	// The snapshot calls return the snapshot of each disk they took. The ID
+	// is set as soon as a snapshot is taken, so that a failed create leaves
+	// the snapshots in state to be deleted.
+	taken := map[string]client.Snapshot{}
+	if len(devices) == 0 {
+		created, err := apiClient.SnapshotInstance(instanceUUID, true, "")
+		if err != nil {
+			return diag.Errorf("Unable to snapshot instance: %v", err)
+		}
+		setSnapshotsID(d, taken, created)
+	}
+	for _, device := range devices {
+		created, err := apiClient.SnapshotInstance(instanceUUID, false, device)
+		if err != nil {
+			return diag.Errorf("Unable to snapshot instance disk %s: %v",
+				device, err)
+		}
+		if len(created) == 0 {
+			return diag.Errorf("No snapshot returned for instance disk %s",
+				device)
+		}
+		setSnapshotsID(d, taken, created)
+	}
+	if len(taken) == 0 {
+		return diag.Errorf("No snapshot returned for instance %s",
+			instanceUUID)
+	}
+	uuids := strings.Split(d.Id(), ",")
+
+	err := resource.RetryContext(ctx, d.Timeout(schema.TimeoutCreate),
+		func() *resource.RetryError {
+
+			all, err := apiClient.GetInstanceSnapshots(instanceUUID)
+			if err != nil {
+				return retryError(err, "Unable to retrieve instance snapshots")
+			}
+
+			byUUID := map[string]client.Snapshot{}
+			for _, s := range all {
+				byUUID[s.UUID] = s
+			}
+
+			for _, uuid := range uuids {
+				s, ok := byUUID[uuid]
+				if !ok {
+					return resource.RetryableError(fmt.Errorf(
+						"snapshot of disk %s not found", taken[uuid].Device))
+				}
+				if s.State == "error" {
+					return resource.NonRetryableError(fmt.Errorf(
+						"snapshot of disk %s in error state", s.Device))
+				}
+				if s.State != "created" {
+					return resource.RetryableError(fmt.Errorf(
+						"snapshot of disk %s not created", s.Device))
+				}
+			}
+
+			return nil
+		},
+	)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceReadInstanceSnapshot(ctx, d, m)
+}
+
+// setSnapshotsID adds the created snapshots to those taken, and sets the ID
+// of the resource to the sorted UUIDs of the snapshots taken.
+func setSnapshotsID(d *schema.ResourceData, taken,
+	created map[string]client.Snapshot) {
+
+	for _, s := range created {
+		taken[s.UUID] = s
+	}
+
+	var uuids []string
+	for uuid := range taken {
+		uuids = append(uuids, uuid)
+	}
+	if len(uuids) == 0 {
+		return
+	}
+	sort.Strings(uuids)
+	d.SetId(strings.Join(uuids, ","))
+}
+
+func resourceReadInstanceSnapshot(ctx context.Context,
+	d *schema.ResourceData, m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
+
+	all, err := apiClient.GetInstanceSnapshots(d.Get("instance_uuid").(string))
+	if err != nil {
+		if isNotFound(err) {
+			log.Printf("[WARN] Instance %s not found, removing snapshot %s "+
+				"from state", d.Get("instance_uuid").(string), d.Id())
+			d.SetId("")
+			return nil
+		}
+		return diag.Errorf("Unable to retrieve instance snapshots: %v", err)
+	}
+
+	byUUID := map[string]client.Snapshot{}
+	for _, s := range all {
+		byUUID[s.UUID] = s
+	}
+
+	var snapshots []map[string]interface{}
+	for _, uuid := range strings.Split(d.Id(), ",") {
+		s, ok := byUUID[uuid]
+		if !ok || s.State == "deleted" {
+			log.Printf("[WARN] Snapshot %s not found, removing from state",
+				uuid)
+			d.SetId("")
+			return nil
+		}
+
+		snapshots = append(snapshots, map[string]interface{}{
+			"device": s.Device,
+			"uuid":   s.UUID,
+			"url":    snapshotURL(s.UUID),
+		})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i]["device"].(string) <
+			snapshots[j]["device"].(string)
+	})
+	if err := d.Set("snapshots", snapshots); err != nil {
+		return diag.Errorf("Snapshots cannot be set: %v", err)
+	}
+
+	return nil
+}
+
+func resourceDeleteInstanceSnapshot(ctx context.Context,
+	d *schema.ResourceData, m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
+
+	for _, uuid := range strings.Split(d.Id(), ",") {
+		err := apiClient.DeleteSnapshot(uuid)
+		if err != nil && !isNotFound(err) {
+			return diag.Errorf("Unable to delete snapshot %s: %v", uuid, err)
+		}
+	}
+
+	d.SetId("")
+	return nil
+}
+
+// snapshotURL returns the URL used to refer to the snapshot as the base of an
+// instance disk.
+func snapshotURL(uuid string) string {
+	return "snapshot:" + uuid
+}
@@ -0,0 +1,149 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	client "github.com/shakenfist/client-go"
+)
+
+func TestAccShakenFistInstanceSnapshot(t *testing.T) {
+	var snapshotID string
+
+	randomName := acctest.RandStringFromCharSet(10, acctest.CharSetAlphaNum)
+
+	resName := "shakenfist_instance_snapshot.jump"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckInstanceSnapshotDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceInstanceSnapshot(randomName, "1"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccSnapshotID(resName, &snapshotID),
+					resource.TestCheckResourceAttr(
+						resName, "snapshots.#", "1"),
+					resource.TestCheckResourceAttr(
+						resName, "snapshots.0.device", "vda"),
+					resource.TestCheckResourceAttrSet(
+						resName, "snapshots.0.uuid"),
+					resource.TestCheckResourceAttrSet(
+						resName, "snapshots.0.url"),
+				),
+			},
+			{
+				// Changing the keepers takes a new snapshot
+				Config: testAccResourceInstanceSnapshot(randomName, "2"),
+				Check: resource.ComposeTestCheckFunc(
+					func(s *terraform.State) error {
+						rs := s.RootModule().Resources[resName]
+						if rs.Primary.ID == snapshotID {
+							return fmt.Errorf("No new snapshot was taken")
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func testAccResourceInstanceSnapshot(randomName, release string) string {
+	res := `
+	resource "shakenfist_instance" "jump" {
+		name = "testacc-{name}-jump"
+		cpus = 1
+		memory = 1024
+		disk {
+			size = 8
+			base = "cirros"
+			bus = "ide"
+			type = "disk"
+		}
+	}
+
+	resource "shakenfist_instance_snapshot" "jump" {
+		instance_uuid = shakenfist_instance.jump.id
+		devices = ["vda"]
+		keepers = {
+			release = "{release}"
+		}
+	}`
+
+	r := strings.NewReplacer("{name}", randomName, "{release}", release)
+	return r.Replace(res)
+}
+
+func testAccSnapshotID(n string, id *string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+
+		*id = rs.Primary.ID
+		return nil
+	}
+}
+
+// testAccCheckInstanceSnapshotDestroy checks the snapshots of destroyed
+// resources are deleted.
+func testAccCheckInstanceSnapshotDestroy(s *terraform.State) error {
+	apiClient := testAccProvider.Meta().(*providerMeta).client
+
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "shakenfist_instance_snapshot" {
+			continue
+		}
+
+		snapshots, err := apiClient.GetInstanceSnapshots(
+			rs.Primary.Attributes["instance_uuid"])
+		if err != nil {
+			if isNotFound(err) {
+				continue
+			}
+			return err
+		}
+
+		for _, snap := range snapshots {
+			if strings.Contains(rs.Primary.ID, snap.UUID) &&
+				snap.State != "deleted" {
+				return fmt.Errorf("Snapshot %s still exists", snap.UUID)
+			}
+		}
+	}
+
+	return nil
+}
+
+func TestUnitSetSnapshotsID(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceInstanceSnapshot().Schema,
+		map[string]interface{}{"instance_uuid": "inst-1"})
+	taken := map[string]client.Snapshot{}
+
+	setSnapshotsID(d, taken, map[string]client.Snapshot{})
+	if d.Id() != "" {
+		t.Errorf("ID set without a snapshot: %s", d.Id())
+	}
+
+	// Each disk snapshot taken is in the ID before the next is taken
+	setSnapshotsID(d, taken, map[string]client.Snapshot{
+		"vdb": {UUID: "snap-b", Device: "vdb"},
+	})
+	if d.Id() != "snap-b" {
+		t.Errorf("Incorrect ID after the first snapshot: %s", d.Id())
+	}
+	setSnapshotsID(d, taken, map[string]client.Snapshot{
+		"vda": {UUID: "snap-a", Device: "vda"},
+	})
+	if d.Id() != "snap-a,snap-b" {
+		t.Errorf("Incorrect ID after the second snapshot: %s", d.Id())
+	}
+}
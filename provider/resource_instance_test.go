@@ -1,14 +1,17 @@
 package provider
 
 import (
+	"context"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
-	"github.com/hashicorp/terraform-plugin-sdk/helper/acctest"
-	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
-	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 	"github.com/shakenfist/client-go"
 )
 
@@ -93,6 +96,341 @@ func TestAccShakenFistInstance(t *testing.T) {
 	})
 }
 
+func TestAccShakenFistInstancePower(t *testing.T) {
+	var instance client.Instance
+
+	randomName := acctest.RandStringFromCharSet(10, acctest.CharSetAlphaNum)
+
+	resName := "shakenfist_instance.jump"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceInstancePower(randomName, "on"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						resName, "power_state", "on"),
+				),
+			},
+			{
+				Config: testAccResourceInstancePower(randomName, "off"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						resName, "power_state", "off"),
+				),
+			},
+			{
+				Config: testAccResourceInstancePower(randomName, "paused"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						resName, "power_state", "paused"),
+				),
+			},
+			{
+				Config: testAccResourceInstancePower(randomName, "on"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckInstanceExists(resName, &instance),
+					resource.TestCheckResourceAttr(
+						resName, "power_state", "on"),
+				),
+			},
+			{
+				// An instance powered off outside Terraform is powered on
+				PreConfig: func() {
+					apiClient := testAccProvider.Meta().(*providerMeta).client
+					err := setInstancePowerState(context.Background(),
+						apiClient, instance.UUID, "off", 5*time.Minute)
+					if err != nil {
+						t.Fatalf("Unable to power off instance: %v", err)
+					}
+				},
+				Config: testAccResourceInstancePower(randomName, "on"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckInstanceNotRecreated(resName, &instance),
+					testAccCheckInstancePowerState(resName, "on"),
+					resource.TestCheckResourceAttr(
+						resName, "power_state", "on"),
+				),
+			},
+		},
+	})
+}
+
+// testAccCheckInstancePowerState checks the power state reported by Shaken
+// Fist, rather than the state recorded by Terraform.
+func testAccCheckInstancePowerState(
+	n, powerState string) resource.TestCheckFunc {
+
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+
+		apiClient := testAccProvider.Meta().(*providerMeta).client
+		inst, err := apiClient.GetInstance(rs.Primary.ID)
+		if err != nil {
+			return fmt.Errorf("Instance cannot be retrieved: %v", err)
+		}
+		if inst.PowerState != powerState {
+			return fmt.Errorf("Instance power state is %s, not %s",
+				inst.PowerState, powerState)
+		}
+		return nil
+	}
+}
+
+func testAccResourceInstancePower(randomName, powerState string) string {
+	res := `
+	resource "shakenfist_instance" "jump" {
+		name = "testacc-{name}-jump"
+		cpus = 1
+		memory = 1024
+		disk {
+			size = 8
+			base = "cirros"
+			bus = "ide"
+			type = "disk"
+		}
+		desired_power_state = "{power}"
+	}`
+
+	r := strings.NewReplacer("{name}", randomName, "{power}", powerState)
+	return r.Replace(res)
+}
+
+func TestAccShakenFistInstanceReboot(t *testing.T) {
+	var instance client.Instance
+
+	randomName := acctest.RandStringFromCharSet(10, acctest.CharSetAlphaNum)
+
+	resName := "shakenfist_instance.jump"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceInstanceReboot(
+					randomName, "soft", "1", "on"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckInstanceExists(resName, &instance),
+					testAccCheckInstanceReboots(resName, 0),
+					resource.TestCheckResourceAttr(
+						resName, "reboot_triggers.config", "1"),
+				),
+			},
+			{
+				Config: testAccResourceInstanceReboot(
+					randomName, "soft", "2", "on"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckInstanceNotRecreated(resName, &instance),
+					testAccCheckInstanceReboots(resName, 1),
+					resource.TestCheckResourceAttr(
+						resName, "power_state", "on"),
+				),
+			},
+			{
+				Config: testAccResourceInstanceReboot(
+					randomName, "hard", "3", "on"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckInstanceNotRecreated(resName, &instance),
+					testAccCheckInstanceReboots(resName, 2),
+					resource.TestCheckResourceAttr(
+						resName, "power_state", "on"),
+				),
+			},
+			{
+				Config: testAccResourceInstanceReboot(
+					randomName, "hard", "3", "off"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckInstanceNotRecreated(resName, &instance),
+					resource.TestCheckResourceAttr(
+						resName, "power_state", "off"),
+				),
+			},
+			{
+				// An instance that is powered off cannot be rebooted
+				Config: testAccResourceInstanceReboot(
+					randomName, "hard", "4", "off"),
+				ExpectError: regexp.MustCompile("cannot be rebooted"),
+			},
+		},
+	})
+}
+
+// testAccCheckInstanceReboots checks the number of reboots of the instance
+// counted by the fake Shaken Fist API. Shaken Fist does not count reboots, so
+// against a cluster the check always passes.
+func testAccCheckInstanceReboots(n string, reboots int) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+		if testAccMockServer == nil {
+			return nil
+		}
+
+		actual := testAccMockServer.instanceReboots(rs.Primary.ID)
+		if actual != reboots {
+			return fmt.Errorf("Instance rebooted %d times, expected %d",
+				actual, reboots)
+		}
+		return nil
+	}
+}
+
+func testAccResourceInstanceReboot(
+	randomName, rebootType, trigger, powerState string) string {
+
+	res := `
+	resource "shakenfist_instance" "jump" {
+		name = "testacc-{name}-jump"
+		cpus = 1
+		memory = 1024
+		disk {
+			size = 8
+			base = "cirros"
+			bus = "ide"
+			type = "disk"
+		}
+		desired_power_state = "{power}"
+		reboot_type = "{type}"
+		reboot_triggers = {
+			config = "{trigger}"
+		}
+	}`
+
+	r := strings.NewReplacer("{name}", randomName, "{type}", rebootType,
+		"{trigger}", trigger, "{power}", powerState)
+	return r.Replace(res)
+}
+
+// TestAccShakenFistInstancePlacement tests affinity and anti-affinity groups.
+// The cluster must have at least two nodes.
+func TestAccShakenFistInstancePlacement(t *testing.T) {
+	randomName := acctest.RandStringFromCharSet(10, acctest.CharSetAlphaNum)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceInstancePlacement(randomName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckInstanceNodes("shakenfist_instance.db1",
+						"shakenfist_instance.db2", false),
+					testAccCheckInstanceNodes("shakenfist_instance.web1",
+						"shakenfist_instance.web2", true),
+					resource.TestCheckResourceAttr("shakenfist_instance.db1",
+						"placement.0.anti_affinity_group",
+						"testacc-"+randomName+"-db"),
+					resource.TestCheckNoResourceAttr("shakenfist_instance.db1",
+						"metadata.anti_affinity_group"),
+				),
+			},
+		},
+	})
+}
+
+func testAccResourceInstancePlacement(randomName string) string {
+	res := `
+	resource "shakenfist_instance" "db1" {
+		name = "testacc-{name}-db1"
+		cpus = 1
+		memory = 1024
+		disk {
+			size = 8
+			base = "cirros"
+			bus = "ide"
+			type = "disk"
+		}
+		placement {
+			anti_affinity_group = "testacc-{name}-db"
+		}
+	}
+
+	resource "shakenfist_instance" "db2" {
+		name = "testacc-{name}-db2"
+		cpus = 1
+		memory = 1024
+		disk {
+			size = 8
+			base = "cirros"
+			bus = "ide"
+			type = "disk"
+		}
+		placement {
+			anti_affinity_group = "testacc-{name}-db"
+		}
+	}
+
+	resource "shakenfist_instance" "web1" {
+		name = "testacc-{name}-web1"
+		cpus = 1
+		memory = 1024
+		disk {
+			size = 8
+			base = "cirros"
+			bus = "ide"
+			type = "disk"
+		}
+		placement {
+			affinity_group = "testacc-{name}-web"
+		}
+	}
+
+	resource "shakenfist_instance" "web2" {
+		name = "testacc-{name}-web2"
+		cpus = 1
+		memory = 1024
+		disk {
+			size = 8
+			base = "cirros"
+			bus = "ide"
+			type = "disk"
+		}
+		placement {
+			affinity_group = "testacc-{name}-web"
+		}
+	}`
+
+	r := strings.NewReplacer("{name}", randomName)
+	return r.Replace(res)
+}
+
+// testAccCheckInstanceNodes checks whether two instances run on the same
+// node.
+func testAccCheckInstanceNodes(a, b string, same bool) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		var nodes []string
+		for _, n := range []string{a, b} {
+			rs, ok := s.RootModule().Resources[n]
+			if !ok {
+				return fmt.Errorf("Not found: %s", n)
+			}
+			if rs.Primary.Attributes["node"] == "" {
+				return fmt.Errorf("Instance %s has no node", n)
+			}
+			nodes = append(nodes, rs.Primary.Attributes["node"])
+		}
+
+		if same && nodes[0] != nodes[1] {
+			return fmt.Errorf("Instances run on different nodes: %s, %s",
+				nodes[0], nodes[1])
+		}
+		if !same && nodes[0] == nodes[1] {
+			return fmt.Errorf("Instances run on the same node: %s",
+				nodes[0])
+		}
+
+		return nil
+	}
+}
+
 func testAccResourceInstance1(randomName string) string {
 	res := `
 	resource "shakenfist_instance" "jump" {
@@ -251,7 +589,7 @@ func testAccInstanceVideo(
 		}
 
 		// Retrieve the configured instance from the test setup
-		apiClient := testAccProvider.Meta().(*client.Client)
+		apiClient := testAccProvider.Meta().(*providerMeta).client
 		inst, err := apiClient.GetInstance(rs.Primary.ID)
 		if err != nil {
 			return fmt.Errorf("Instance (%s) cannot be retrieved: %v",
@@ -278,7 +616,7 @@ func testAccInstanceMetadata(
 		}
 
 		// Retrieve the configured instance from the test setup
-		apiClient := testAccProvider.Meta().(*client.Client)
+		apiClient := testAccProvider.Meta().(*providerMeta).client
 		serverMeta, err := apiClient.GetInstanceMetadata(rs.Primary.ID)
 		if err != nil {
 			return fmt.Errorf("Instance (%s) metadata cannot be retrieved: %v",
@@ -298,7 +636,7 @@ func testAccCheckInstanceExists(n string, instance *client.Instance) resource.Te
 		}
 
 		// Retrieve the configured instance from the test setup
-		apiClient := testAccProvider.Meta().(*client.Client)
+		apiClient := testAccProvider.Meta().(*providerMeta).client
 		resp, err := apiClient.GetInstance(rs.Primary.ID)
 		if err != nil {
 			return fmt.Errorf("Instance (%s) cannot be retrieved: %v", rs.Primary.ID, err)
@@ -310,6 +648,26 @@ func testAccCheckInstanceExists(n string, instance *client.Instance) resource.Te
 	}
 }
 
+// testAccCheckInstanceNotRecreated checks the instance UUID is the same as
+// the previously retrieved instance.
+func testAccCheckInstanceNotRecreated(
+	n string, instance *client.Instance) resource.TestCheckFunc {
+
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+
+		if rs.Primary.ID != instance.UUID {
+			return fmt.Errorf("Instance was recreated: %s (was %s)",
+				rs.Primary.ID, instance.UUID)
+		}
+
+		return nil
+	}
+}
+
 func testAccInterfaceOrder(n string,
 	netOrder []string) resource.TestCheckFunc {
 
@@ -321,7 +679,7 @@ func testAccInterfaceOrder(n string,
 		}
 
 		// Retrieve the instance interfaces from the test setup
-		apiClient := testAccProvider.Meta().(*client.Client)
+		apiClient := testAccProvider.Meta().(*providerMeta).client
 		interfaces, err := apiClient.GetInstanceInterfaces(rs.Primary.ID)
 		if err != nil {
 			return fmt.Errorf(
@@ -367,7 +725,7 @@ func testAccInstanceNetwork(n string,
 		}
 
 		// Retrieve the configured instance from the test setup
-		apiClient := testAccProvider.Meta().(*client.Client)
+		apiClient := testAccProvider.Meta().(*providerMeta).client
 		interfaces, err := apiClient.GetInstanceInterfaces(rs.Primary.ID)
 		if err != nil {
 			return fmt.Errorf("Instance (%s) cannot be retrieved: %v",
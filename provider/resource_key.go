@@ -1,14 +1,23 @@
 package provider
 
 import (
+	"context"
+	"crypto/rand"
 	"fmt"
+	"log"
+	"math/big"
+	"strings"
 
-	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
-	client "github.com/shakenfist/client-go"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 )
 
+// keyCharacters is the default character set of generated keys.
+const keyCharacters = "abcdefghijklmnopqrstuvwxyz" +
+	"ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
 func resourceKey() *schema.Resource {
-	fmt.Print()
 	return &schema.Resource{
 		Schema: map[string]*schema.Schema{
 			"namespace": {
@@ -25,86 +34,280 @@ func resourceKey() *schema.Resource {
 			},
 			"key": {
 				Type:        schema.TypeString,
-				Required:    true,
+				Optional:    true,
+				Computed:    true,
 				Sensitive:   true,
-				Description: "The access key",
+				Description: "The access key, generated if not set",
+			},
+			"key_length": {
+				Type:          schema.TypeInt,
+				Optional:      true,
+				Default:       32,
+				Description:   "Length of a generated key",
+				ConflictsWith: []string{"key"},
+				ValidateDiagFunc: validation.ToDiagFunc(
+					validation.IntBetween(16, 256)),
+			},
+			"key_characters": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Default:       keyCharacters,
+				Description:   "Characters used in a generated key",
+				ConflictsWith: []string{"key"},
+				ValidateDiagFunc: validation.ToDiagFunc(
+					validation.StringLenBetween(2, 256)),
+			},
+			"rotation_triggers": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				Description: "Arbitrary values " +
+					"that generate a new key when changed",
+				ConflictsWith: []string{"key"},
+				Elem: &schema.Schema{
+					Type: schema.TypeString},
 			},
 		},
-		Create: resourceCreateKey,
-		Read:   resourceReadKey,
-		Delete: resourceDeleteKey,
-		Exists: resourceExistsKey,
-		Update: resourceUpdateKey,
+		CustomizeDiff: resourceKeyCustomizeDiff,
+		CreateContext: resourceCreateKey,
+		ReadContext:   resourceReadKey,
+		DeleteContext: resourceDeleteKey,
+		UpdateContext: resourceUpdateKey,
 		Importer: &schema.ResourceImporter{
-			State: schema.ImportStatePassthrough,
+			StateContext: resourceImportKey,
+		},
+		SchemaVersion: 1,
+		StateUpgraders: []schema.StateUpgrader{
+			{
+				Type:    resourceKeyV0().CoreConfigSchema().ImpliedType(),
+				Upgrade: resourceKeyStateUpgradeV0,
+				Version: 0,
+			},
 		},
 	}
 }
 
-func resourceCreateKey(d *schema.ResourceData, m interface{}) error {
-	apiClient := m.(*client.Client)
+// resourceKeyV0 is the key schema before the ID included the namespace.
+func resourceKeyV0() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"namespace": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"keyname": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"key": {
+				Type:      schema.TypeString,
+				Required:  true,
+				Sensitive: true,
+			},
+		},
+	}
+}
 
-	err := apiClient.CreateNamespaceKey(
-		d.Get("namespace").(string),
-		d.Get("keyname").(string),
-		d.Get("key").(string),
-	)
-	if err != nil {
-		return fmt.Errorf("Unable to create key: %v", err)
+// resourceKeyStateUpgradeV0 changes the key ID from the key name to the
+// namespace and key name, and sets the defaults of the key generation
+// arguments added in version 1, as an imported key has.
+func resourceKeyStateUpgradeV0(ctx context.Context,
+	rawState map[string]interface{}, meta interface{}) (map[string]interface{},
+	error) {
+
+	namespace, _ := rawState["namespace"].(string)
+	keyname, _ := rawState["keyname"].(string)
+	if namespace == "" || keyname == "" {
+		return nil, fmt.Errorf("Key state has no namespace or keyname")
 	}
 
-	d.SetId(d.Get("keyname").(string))
+	rawState["id"] = keyID(namespace, keyname)
+	rawState["key_length"] = 32
+	rawState["key_characters"] = keyCharacters
+	return rawState, nil
+}
 
-	return nil
+// keyID returns the ID of the key in the namespace.
+func keyID(namespace, keyname string) string {
+	return namespace + "/" + keyname
 }
 
-func resourceReadKey(d *schema.ResourceData, m interface{}) error {
-	// No data can be read from a namespace access key.
-	return nil
+// parseKeyID returns the namespace and key name of a key ID.
+func parseKeyID(id string) (string, string, error) {
+	parts := strings.SplitN(id, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf(
+			"Key ID %s is not of the form namespace/keyname", id)
+	}
+	return parts[0], parts[1], nil
 }
 
-func resourceDeleteKey(d *schema.ResourceData, m interface{}) error {
-	apiClient := m.(*client.Client)
+// resourceImportKey imports a key from its namespace/keyname ID. The key
+// itself cannot be read from Shaken Fist, so it is set by the next apply if
+// configured.
+func resourceImportKey(ctx context.Context, d *schema.ResourceData,
+	m interface{}) ([]*schema.ResourceData, error) {
 
-	err := apiClient.DeleteNamespaceKey(d.Get("namespace").(string), d.Id())
+	namespace, keyname, err := parseKeyID(d.Id())
 	if err != nil {
-		return fmt.Errorf("Unable to delete namespace key: %v", err)
+		return nil, err
 	}
-	d.SetId("")
+
+	if err := d.Set("namespace", namespace); err != nil {
+		return nil, fmt.Errorf("Key namespace cannot be set: %v", err)
+	}
+	if err := d.Set("keyname", keyname); err != nil {
+		return nil, fmt.Errorf("Key keyname cannot be set: %v", err)
+	}
+	if err := d.Set("key_length", 32); err != nil {
+		return nil, fmt.Errorf("Key key_length cannot be set: %v", err)
+	}
+	if err := d.Set("key_characters", keyCharacters); err != nil {
+		return nil, fmt.Errorf("Key key_characters cannot be set: %v", err)
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
+
+func resourceCreateKey(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
+
+	key := d.Get("key").(string)
+	if key == "" {
+		var err error
+		key, err = generateKey(d.Get("key_length").(int),
+			d.Get("key_characters").(string))
+		if err != nil {
+			return diag.Errorf("Unable to generate key: %v", err)
+		}
+	}
+
+	err := apiClient.CreateNamespaceKey(
+		d.Get("namespace").(string),
+		d.Get("keyname").(string),
+		key,
+	)
+	if err != nil {
+		return diag.Errorf("Unable to create key: %v", err)
+	}
+
+	d.SetId(keyID(d.Get("namespace").(string), d.Get("keyname").(string)))
+
+	if err := d.Set("key", key); err != nil {
+		return diag.Errorf("Key cannot be set: %v", err)
+	}
+
 	return nil
 }
 
-func resourceExistsKey(d *schema.ResourceData, m interface{}) (bool, error) {
-	apiClient := m.(*client.Client)
+func resourceReadKey(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
 
+	// Only the existence of a namespace access key can be read, the key
+	// itself is never returned by Shaken Fist.
 	keynames, err := apiClient.GetNamespaceKeys(d.Get("namespace").(string))
 	if err != nil {
-		return false, fmt.Errorf("Unable to retrieve namespace keys: %v", err)
+		if isNotFound(err) {
+			log.Printf("[WARN] Namespace %s not found, removing key from state",
+				d.Get("namespace").(string))
+			d.SetId("")
+			return nil
+		}
+		return diag.Errorf("Unable to retrieve namespace keys: %v", err)
 	}
 
 	for _, n := range keynames {
-		if n == d.Id() {
-			return true, nil
+		if n == d.Get("keyname").(string) {
+			return nil
 		}
 	}
 
-	return false, nil
+	log.Printf("[WARN] Key %s not found, removing from state", d.Id())
+	d.SetId("")
+	return nil
+}
+
+func resourceDeleteKey(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
+
+	err := apiClient.DeleteNamespaceKey(d.Get("namespace").(string),
+		d.Get("keyname").(string))
+	if err != nil && !isNotFound(err) {
+		return diag.Errorf("Unable to delete namespace key: %v", err)
+	}
+	d.SetId("")
+	return nil
 }
 
-func resourceUpdateKey(d *schema.ResourceData, m interface{}) error {
-	apiClient := m.(*client.Client)
+func resourceUpdateKey(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
 
 	if d.HasChange("key") {
+		key := d.Get("key").(string)
+		if key == "" {
+			// The key is unknown in the plan when it is rotated
+			var err error
+			key, err = generateKey(d.Get("key_length").(int),
+				d.Get("key_characters").(string))
+			if err != nil {
+				return diag.Errorf("Unable to generate key: %v", err)
+			}
+		}
+
 		err := apiClient.UpdateNamespaceKey(
 			d.Get("namespace").(string),
 			d.Get("keyname").(string),
-			d.Get("key").(string),
+			key,
 		)
 		if err != nil {
-			return fmt.Errorf(
+			return diag.Errorf(
 				"UpdateNamespace: cannot update namespace key: %v", err)
 		}
+
+		if err := d.Set("key", key); err != nil {
+			return diag.Errorf("Key cannot be set: %v", err)
+		}
 	}
 
 	return nil
 }
+
+// resourceKeyCustomizeDiff plans a new generated key when the rotation
+// triggers change.
+func resourceKeyCustomizeDiff(ctx context.Context, d *schema.ResourceDiff,
+	m interface{}) error {
+
+	if d.Id() != "" && d.HasChange("rotation_triggers") {
+		return d.SetNewComputed("key")
+	}
+	return nil
+}
+
+// generateKey returns a random key of the given length drawn from the
+// characters.
+func generateKey(length int, characters string) (string, error) {
+	chars := []rune(characters)
+	if len(chars) < 2 {
+		return "", fmt.Errorf("at least two characters are required")
+	}
+
+	key := make([]rune, length)
+	max := big.NewInt(int64(len(chars)))
+	for i := range key {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		key[i] = chars[n.Int64()]
+	}
+	return string(key), nil
+}
@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestUnitGenerateKey(t *testing.T) {
+	key, err := generateKey(40, "ab")
+	if err != nil {
+		t.Fatalf("generateKey returned an error: %v", err)
+	}
+	if len(key) != 40 {
+		t.Errorf("Key has length %d, not 40", len(key))
+	}
+	if strings.Trim(key, "ab") != "" {
+		t.Errorf("Key %s has characters outside the set", key)
+	}
+
+	other, err := generateKey(40, keyCharacters)
+	if err != nil {
+		t.Fatalf("generateKey returned an error: %v", err)
+	}
+	if other == key {
+		t.Errorf("Generated keys are the same")
+	}
+
+	if _, err := generateKey(40, "a"); err == nil {
+		t.Errorf("generateKey accepted a single character")
+	}
+}
+
+func TestUnitKeyStateUpgradeV0(t *testing.T) {
+	state := map[string]interface{}{
+		"id":        "student",
+		"namespace": "lab123",
+		"keyname":   "student",
+		"key":       "secret",
+	}
+	expected := map[string]interface{}{
+		"id":             "lab123/student",
+		"namespace":      "lab123",
+		"keyname":        "student",
+		"key":            "secret",
+		"key_length":     32,
+		"key_characters": keyCharacters,
+	}
+
+	actual, err := resourceKeyStateUpgradeV0(context.Background(), state, nil)
+	if err != nil {
+		t.Fatalf("State upgrade returned an error: %v", err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("State upgraded to %v, expected %v", actual, expected)
+	}
+
+	_, err = resourceKeyStateUpgradeV0(context.Background(),
+		map[string]interface{}{"id": "student"}, nil)
+	if err == nil {
+		t.Errorf("State without a namespace was upgraded")
+	}
+}
+
+func TestUnitParseKeyID(t *testing.T) {
+	namespace, keyname, err := parseKeyID("lab123/student")
+	if err != nil {
+		t.Fatalf("parseKeyID returned an error: %v", err)
+	}
+	if namespace != "lab123" || keyname != "student" {
+		t.Errorf("Key ID parsed to %s and %s", namespace, keyname)
+	}
+
+	for _, id := range []string{"student", "/student", "lab123/"} {
+		if _, _, err := parseKeyID(id); err == nil {
+			t.Errorf("Key ID %s was parsed", id)
+		}
+	}
+}
+
+// TestAccShakenFistKeyGenerated tests a generated key and its rotation.
+//
+// *** NOTE: Only tested when environment namespace set to 'system'.
+func TestAccShakenFistKeyGenerated(t *testing.T) {
+	var key string
+
+	randomName := acctest.RandStringFromCharSet(10, acctest.CharSetAlphaNum)
+
+	resName := "shakenfist_key.key1"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				SkipFunc: testAccSystemKey,
+				Config:   testAccResourceKeyGenerated(randomName, "1"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccKeyValue(resName, &key),
+					func(s *terraform.State) error {
+						if len(key) != 24 {
+							return fmt.Errorf(
+								"Generated key has length %d, not 24",
+								len(key))
+						}
+						return nil
+					},
+				),
+			},
+			{
+				// Changing the rotation triggers generates a new key
+				SkipFunc: testAccSystemKey,
+				Config:   testAccResourceKeyGenerated(randomName, "2"),
+				Check: resource.ComposeTestCheckFunc(
+					func(s *terraform.State) error {
+						rs := s.RootModule().Resources[resName]
+						if rs.Primary.Attributes["key"] == key {
+							return fmt.Errorf("Key was not rotated")
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func testAccResourceKeyGenerated(randomName, rotation string) string {
+	res := `
+	resource "shakenfist_namespace" "testspace" {
+		name = "testacc-{name}"
+	}
+
+	resource "shakenfist_key" "key1" {
+		namespace = shakenfist_namespace.testspace.name
+		keyname = "testkey1"
+		key_length = 24
+		rotation_triggers = {
+			rotation = "{rotation}"
+		}
+	}
+	`
+
+	rName := strings.NewReplacer("{name}", randomName,
+		"{rotation}", rotation)
+
+	return rName.Replace(res)
+}
+
+func testAccKeyValue(n string, key *string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+
+		*key = rs.Primary.Attributes["key"]
+		if *key == "" {
+			return fmt.Errorf("Key was not generated")
+		}
+		return nil
+	}
+}
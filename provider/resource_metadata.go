@@ -0,0 +1,165 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	client "github.com/shakenfist/client-go"
+)
+
+// metadataTypes maps the object types accepted by shakenfist_metadata to the
+// Shaken Fist resource types.
+var metadataTypes = map[string]client.ResourceType{
+	"instance":  client.TypeInstance,
+	"network":   client.TypeNetwork,
+	"namespace": client.TypeNamespace,
+}
+
+// resourceMetadata manages metadata keys on an object owned elsewhere. Unlike
+// the metadata argument of the object resources, it only changes the keys it
+// manages, so other tools can set their own keys on the same object. It must
+// not be used on an object whose authoritative metadata argument is managed.
+func resourceMetadata() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"object_type": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+				Description: "The type of the object: " +
+					"instance, network or namespace",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(
+					[]string{"instance", "network", "namespace"}, false)),
+			},
+			"object_id": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+				Description: "The UUID of the instance or network, " +
+					"or the name of the namespace",
+			},
+			"metadata": {
+				Type:        schema.TypeMap,
+				Required:    true,
+				Description: "The metadata keys and values to manage",
+				Elem: &schema.Schema{
+					Type: schema.TypeString},
+			},
+		},
+		CreateContext: resourceCreateMetadata,
+		ReadContext:   resourceReadMetadata,
+		DeleteContext: resourceDeleteMetadata,
+		UpdateContext: resourceUpdateMetadata,
+	}
+}
+
+func resourceCreateMetadata(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
+
+	resType := metadataTypes[d.Get("object_type").(string)]
+	objectID := d.Get("object_id").(string)
+
+	for k, v := range d.Get("metadata").(map[string]interface{}) {
+		err := apiClient.SetMetadata(resType, objectID, k, v.(string))
+		if err != nil {
+			return diag.Errorf("Unable to set metadata key %s: %v", k, err)
+		}
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s", d.Get("object_type").(string), objectID))
+
+	return resourceReadMetadata(ctx, d, m)
+}
+
+func resourceReadMetadata(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
+
+	resType := metadataTypes[d.Get("object_type").(string)]
+	objectID := d.Get("object_id").(string)
+
+	metadata, err := apiClient.GetMetadata(resType, objectID)
+	if err != nil {
+		if isNotFound(err) {
+			log.Printf("[WARN] %s %s not found, removing metadata from state",
+				d.Get("object_type").(string), objectID)
+			d.SetId("")
+			return nil
+		}
+		return diag.Errorf("Unable to retrieve metadata: %v", err)
+	}
+
+	// Only the managed keys are read, a missing key is set again
+	managed := map[string]string{}
+	for k := range d.Get("metadata").(map[string]interface{}) {
+		if v, ok := metadata[k]; ok {
+			managed[k] = v
+		}
+	}
+	if err := d.Set("metadata", managed); err != nil {
+		return diag.Errorf("Metadata cannot be set: %v", err)
+	}
+
+	return nil
+}
+
+func resourceUpdateMetadata(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
+
+	resType := metadataTypes[d.Get("object_type").(string)]
+	objectID := d.Get("object_id").(string)
+
+	o, n := d.GetChange("metadata")
+	oldMeta := o.(map[string]interface{})
+	newMeta := n.(map[string]interface{})
+
+	for k, v := range newMeta {
+		if oldVal, ok := oldMeta[k]; ok && oldVal == v {
+			continue
+		}
+		err := apiClient.SetMetadata(resType, objectID, k, v.(string))
+		if err != nil {
+			return diag.Errorf("Unable to set metadata key %s: %v", k, err)
+		}
+	}
+
+	for k := range oldMeta {
+		if _, ok := newMeta[k]; ok {
+			continue
+		}
+		err := apiClient.DeleteMetadata(resType, objectID, k)
+		if err != nil && !isNotFound(err) {
+			return diag.Errorf("Unable to delete metadata key %s: %v", k, err)
+		}
+	}
+
+	return resourceReadMetadata(ctx, d, m)
+}
+
+func resourceDeleteMetadata(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
+
+	resType := metadataTypes[d.Get("object_type").(string)]
+	objectID := d.Get("object_id").(string)
+
+	for k := range d.Get("metadata").(map[string]interface{}) {
+		err := apiClient.DeleteMetadata(resType, objectID, k)
+		if err != nil && !isNotFound(err) {
+			return diag.Errorf("Unable to delete metadata key %s: %v", k, err)
+		}
+	}
+
+	d.SetId("")
+	return nil
+}
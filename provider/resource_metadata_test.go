@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	client "github.com/shakenfist/client-go"
+)
+
+func TestAccShakenFistMetadata(t *testing.T) {
+	randomName := acctest.RandStringFromCharSet(10, acctest.CharSetAlphaNum)
+
+	netName := "shakenfist_network.external"
+	resName := "shakenfist_metadata.team"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceMetadata(randomName, "infra"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						resName, "metadata.team", "infra"),
+					testAccNetworkMetadata(netName, map[string]string{
+						"team": "infra",
+					}),
+
+					// Another tool sets its own key
+					testAccSetNetworkMetadata(netName, "inventory", "rack-4"),
+				),
+			},
+			{
+				Config: testAccResourceMetadata(randomName, "platform"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						resName, "metadata.team", "platform"),
+					testAccNetworkMetadata(netName, map[string]string{
+						"team":      "platform",
+						"inventory": "rack-4",
+					}),
+				),
+			},
+		},
+	})
+}
+
+func testAccResourceMetadata(randomName, team string) string {
+	res := `
+	resource "shakenfist_network" "external" {
+		name = "testacc-{name}-external"
+		netblock = "10.0.1.0/24"
+		provide_dhcp = true
+		provide_nat = false
+
+		lifecycle {
+			ignore_changes = [metadata]
+		}
+	}
+
+	resource "shakenfist_metadata" "team" {
+		object_type = "network"
+		object_id = shakenfist_network.external.id
+		metadata = {
+			team = "{team}"
+		}
+	}`
+
+	r := strings.NewReplacer("{name}", randomName, "{team}", team)
+	return r.Replace(res)
+}
+
+// testAccSetNetworkMetadata sets a metadata key directly via the API.
+func testAccSetNetworkMetadata(n, key, value string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+
+		apiClient := testAccProvider.Meta().(*providerMeta).client
+		return apiClient.SetMetadata(client.TypeNetwork, rs.Primary.ID,
+			key, value)
+	}
+}
@@ -1,9 +1,14 @@
 package provider
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"time"
 
-	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	client "github.com/shakenfist/client-go"
 )
 
@@ -20,39 +25,52 @@ func resourceNamespace() *schema.Resource {
 			"metadata": {
 				Type:     schema.TypeMap,
 				Optional: true,
+				Description: "Metadata of the namespace, " +
+					"other keys are removed",
 				Elem: &schema.Schema{
 					Type: schema.TypeString},
 			},
+			"force_destroy": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+				Description: "Delete all floating IPs, instances, " +
+					"networks and keys in the namespace when destroying it",
+			},
 		},
-		Create: resourceCreateNamespace,
-		Read:   resourceReadNamespace,
-		Delete: resourceDeleteNamespace,
-		Exists: resourceExistsNamespace,
-		Update: resourceUpdateNamespace,
+		CreateContext: resourceCreateNamespace,
+		ReadContext:   resourceReadNamespace,
+		DeleteContext: resourceDeleteNamespace,
+		UpdateContext: resourceUpdateNamespace,
 		Importer: &schema.ResourceImporter{
-			State: schema.ImportStatePassthrough,
+			StateContext: resourceImportNamespace,
+		},
+		Timeouts: &schema.ResourceTimeout{
+			Delete: schema.DefaultTimeout(10 * time.Minute),
 		},
 	}
 }
 
-func resourceCreateNamespace(d *schema.ResourceData, m interface{}) error {
-	apiClient := m.(*client.Client)
+func resourceCreateNamespace(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
 	namespace := d.Get("name").(string)
 
 	if err := apiClient.CreateNamespace(namespace); err != nil {
-		return fmt.Errorf("Unable to create namespace: %v", err)
+		return diag.Errorf("Unable to create namespace: %v", err)
 	}
 
 	// Set metadata on namespace
 	for k, v := range d.Get("metadata").(map[string]interface{}) {
 		val, ok := v.(string)
 		if !ok {
-			return fmt.Errorf("Tag value is not a string")
+			return diag.Errorf("Tag value is not a string")
 		}
 
 		err := apiClient.SetMetadata(client.TypeNamespace, namespace, k, val)
 		if err != nil {
-			return fmt.Errorf("CreateNamespace cannot store metadata: %v", err)
+			return diag.Errorf("CreateNamespace cannot store metadata: %v", err)
 		}
 	}
 
@@ -61,55 +79,225 @@ func resourceCreateNamespace(d *schema.ResourceData, m interface{}) error {
 	return nil
 }
 
-func resourceReadNamespace(d *schema.ResourceData, m interface{}) error {
-	apiClient := m.(*client.Client)
+// resourceImportNamespace imports a namespace by name. force_destroy is set
+// to its default, as it is not held by Shaken Fist.
+func resourceImportNamespace(ctx context.Context, d *schema.ResourceData,
+	m interface{}) ([]*schema.ResourceData, error) {
+
+	if err := d.Set("force_destroy", false); err != nil {
+		return nil, fmt.Errorf("Namespace force_destroy cannot be set: %v",
+			err)
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
+
+func resourceReadNamespace(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
+
+	namespaces, err := apiClient.GetNamespaces()
+	if err != nil {
+		return diag.Errorf("Unable to retrieve namespaces: %v", err)
+	}
+
+	exists := false
+	for _, n := range namespaces {
+		if n == d.Id() {
+			exists = true
+			break
+		}
+	}
+	if !exists {
+		log.Printf("[WARN] Namespace %s not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	if err := d.Set("name", d.Id()); err != nil {
+		return diag.Errorf("Namespace name cannot be set: %v", err)
+	}
 
 	// Retrieve metadata
 	metadata, err := apiClient.GetMetadata(client.TypeNamespace, d.Id())
 	if err != nil {
-		return fmt.Errorf("ReadNamespace unable to retrieve metadata: %v", err)
+		return diag.Errorf("ReadNamespace unable to retrieve metadata: %v", err)
 	}
 	if err := d.Set("metadata", metadata); err != nil {
-		return fmt.Errorf("Namespace Metadata cannot be set: %v", err)
+		return diag.Errorf("Namespace Metadata cannot be set: %v", err)
 	}
 
 	return nil
 }
 
-func resourceDeleteNamespace(d *schema.ResourceData, m interface{}) error {
-	apiClient := m.(*client.Client)
+func resourceDeleteNamespace(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
+
+	if d.Get("force_destroy").(bool) {
+		err := destroyNamespaceContents(ctx, apiClient, d.Id(),
+			d.Timeout(schema.TimeoutDelete))
+		if err != nil {
+			return diag.Errorf("Unable to empty namespace: %v", err)
+		}
+	}
 
 	err := apiClient.DeleteNamespace(d.Id())
-	if err != nil {
-		return fmt.Errorf("Unable to delete namespace: %v", err)
+	if err != nil && !isNotFound(err) {
+		return diag.Errorf("Unable to delete namespace: %v", err)
 	}
 	d.SetId("")
 	return nil
 }
 
-func resourceExistsNamespace(d *schema.ResourceData, m interface{}) (bool, error) {
-	apiClient := m.(*client.Client)
+// destroyNamespaceContents deletes everything in the namespace in dependency
+// order: floating IPs are removed from instances, instances are deleted
+// before the networks they use, then the keys are deleted. Instances and
+// networks are deleted once they reach the deleted state. All of the waits
+// share the timeout.
+func destroyNamespaceContents(ctx context.Context, apiClient *client.Client,
+	namespace string, timeout time.Duration) error {
 
-	namespaces, err := apiClient.GetNamespaces()
+	deadline := time.Now().Add(timeout)
+
+	instances, err := apiClient.GetInstances()
 	if err != nil {
-		return false, fmt.Errorf("Unable to retrieve namespaces: %v", err)
+		return fmt.Errorf("Unable to retrieve instances: %v", err)
 	}
 
-	for _, n := range namespaces {
-		if n == d.Id() {
-			return true, nil
+	var instanceUUIDs []string
+	for _, inst := range instances {
+		if inst.Namespace != namespace || inst.State == "deleted" {
+			continue
+		}
+		instanceUUIDs = append(instanceUUIDs, inst.UUID)
+
+		interfaces, err := apiClient.GetInstanceInterfaces(inst.UUID)
+		if err != nil && !isNotFound(err) {
+			return fmt.Errorf("Unable to retrieve interfaces of instance "+
+				"%s: %v", inst.UUID, err)
+		}
+		for _, iface := range interfaces {
+			if iface.Floating == "" {
+				continue
+			}
+			err := apiClient.DefloatInterface(iface.UUID)
+			if err != nil && !isNotFound(err) {
+				return fmt.Errorf("Unable to remove floating IP from "+
+					"interface %s: %v", iface.UUID, err)
+			}
+		}
+
+		log.Printf("[INFO] Deleting instance %s in namespace %s",
+			inst.UUID, namespace)
+		err = apiClient.DeleteInstance(inst.UUID)
+		if err != nil && !isNotFound(err) {
+			return fmt.Errorf("Unable to delete instance %s: %v",
+				inst.UUID, err)
+		}
+	}
+
+	for _, uuid := range instanceUUIDs {
+		err := waitDeleted(ctx, deadline, "instance", uuid,
+			func() (string, error) {
+				i, err := apiClient.GetInstance(uuid)
+				return i.State, err
+			})
+		if err != nil {
+			return err
+		}
+	}
+
+	networks, err := apiClient.GetNetworks()
+	if err != nil {
+		return fmt.Errorf("Unable to retrieve networks: %v", err)
+	}
+
+	var networkUUIDs []string
+	for _, n := range networks {
+		if n.Namespace != namespace || n.State == "deleted" {
+			continue
+		}
+		networkUUIDs = append(networkUUIDs, n.UUID)
+
+		log.Printf("[INFO] Deleting network %s in namespace %s",
+			n.UUID, namespace)
+		err := apiClient.DeleteNetwork(n.UUID)
+		if err != nil && !isNotFound(err) {
+			return fmt.Errorf("Unable to delete network %s: %v", n.UUID, err)
 		}
 	}
 
-	return false, nil
+	for _, uuid := range networkUUIDs {
+		err := waitDeleted(ctx, deadline, "network", uuid,
+			func() (string, error) {
+				n, err := apiClient.GetNetwork(uuid)
+				return n.State, err
+			})
+		if err != nil {
+			return err
+		}
+	}
+
+	keynames, err := apiClient.GetNamespaceKeys(namespace)
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("Unable to retrieve namespace keys: %v", err)
+	}
+	for _, keyname := range keynames {
+		err := apiClient.DeleteNamespaceKey(namespace, keyname)
+		if err != nil && !isNotFound(err) {
+			return fmt.Errorf("Unable to delete key %s: %v", keyname, err)
+		}
+	}
+
+	return nil
 }
 
-func resourceUpdateNamespace(d *schema.ResourceData, m interface{}) error {
-	apiClient := m.(*client.Client)
+// waitDeleted polls the state of an object until it is deleted or no longer
+// found.
+func waitDeleted(ctx context.Context, deadline time.Time, kind,
+	uuid string, getState func() (string, error)) error {
+
+	timeout := time.Until(deadline)
+	if timeout <= 0 {
+		return fmt.Errorf("timeout while waiting for %s %s to be deleted",
+			kind, uuid)
+	}
+
+	return resource.RetryContext(ctx, timeout, func() *resource.RetryError {
+		state, err := getState()
+		if err != nil {
+			if isNotFound(err) {
+				return nil
+			}
+			return retryError(err, "Unable to check %s %s", kind, uuid)
+		}
+
+		if state == "error" {
+			return resource.NonRetryableError(fmt.Errorf(
+				"%s %s in error state", kind, uuid))
+		}
+		if state != "deleted" {
+			return resource.RetryableError(fmt.Errorf(
+				"%s %s not deleted", kind, uuid))
+		}
+		return nil
+	})
+}
+
+func resourceUpdateNamespace(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
 
 	if d.HasChange("metadata") {
 		if err := updateMetadata(client.TypeNamespace, d, apiClient); err != nil {
-			return fmt.Errorf("UpdateNamespace error: %v", err)
+			return diag.Errorf("UpdateNamespace error: %v", err)
 		}
 	}
 
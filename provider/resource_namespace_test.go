@@ -6,16 +6,15 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/hashicorp/terraform-plugin-sdk/helper/acctest"
-	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
-	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 	client "github.com/shakenfist/client-go"
 )
 
 // TestAccShakenFistNamespace tests the namespace and key creation.
 //
 // *** NOTE: Only tested when environment namespace set to 'system'.
-//
 func TestAccShakenFistNamespace(t *testing.T) {
 	var keynames []string
 
@@ -45,6 +44,23 @@ func TestAccShakenFistNamespace(t *testing.T) {
 					}),
 				),
 			},
+			{
+				// An imported namespace has the same state as the created one
+				SkipFunc:          testAccSystemKey,
+				ResourceName:      resType + resName,
+				ImportState:       true,
+				ImportStateId:     "testacc-" + randomName,
+				ImportStateVerify: true,
+			},
+			{
+				// The key is not returned by Shaken Fist
+				SkipFunc:                testAccSystemKey,
+				ResourceName:            "shakenfist_key.key1",
+				ImportState:             true,
+				ImportStateId:           "testacc-" + randomName + "/testkey1",
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"key"},
+			},
 		},
 	})
 }
@@ -77,6 +93,112 @@ func testAccResourceNamespace(randomName string) string {
 	return rName.Replace(res)
 }
 
+// TestAccShakenFistNamespaceForceDestroy tests a namespace holding a network
+// and an instance with a floating IP created outside Terraform is emptied and
+// deleted.
+//
+// *** NOTE: Only tested when environment namespace set to 'system'.
+func TestAccShakenFistNamespaceForceDestroy(t *testing.T) {
+	randomName := acctest.RandStringFromCharSet(10, acctest.CharSetAlphaNum)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckNamespaceDestroy,
+		Steps: []resource.TestStep{
+			{
+				SkipFunc: testAccSystemKey,
+				Config:   testAccResourceNamespaceForceDestroy(randomName),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"shakenfist_namespace.testspace", "force_destroy",
+						"true"),
+					testAccNamespaceObjects("testacc-"+randomName),
+				),
+			},
+		},
+	})
+}
+
+func testAccResourceNamespaceForceDestroy(randomName string) string {
+	res := `
+	resource "shakenfist_namespace" "testspace" {
+		name = "testacc-{name}"
+		force_destroy = true
+	}
+
+	resource "shakenfist_key" "key1" {
+		namespace = shakenfist_namespace.testspace.name
+		keyname = "testkey1"
+		key = "secret"
+	}
+	`
+
+	rName := strings.NewReplacer("{name}", randomName)
+
+	return rName.Replace(res)
+}
+
+// testAccNamespaceObjects creates a network and an instance with a floating
+// IP in the namespace, as a user of the namespace would outside Terraform.
+func testAccNamespaceObjects(namespace string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		apiClient := client.NewClient(
+			os.Getenv("SHAKENFIST_API_URL"), namespace, "secret")
+
+		network, err := apiClient.CreateNetwork(
+			"10.0.1.0/24", true, true, "testacc-unmanaged")
+		if err != nil {
+			return fmt.Errorf("Unable to create network in namespace: %v",
+				err)
+		}
+
+		inst, err := apiClient.CreateInstance("testacc-unmanaged", 1, 1024,
+			[]client.NetworkSpec{{NetworkUUID: network.UUID}},
+			[]client.DiskSpec{{Base: "cirros", Size: 8, Type: "disk"}},
+			client.VideoSpec{Model: "cirrus", Memory: 16384}, "", "")
+		if err != nil {
+			return fmt.Errorf("Unable to create instance in namespace: %v",
+				err)
+		}
+
+		interfaces, err := apiClient.GetInstanceInterfaces(inst.UUID)
+		if err != nil {
+			return fmt.Errorf("Unable to retrieve instance interfaces: %v",
+				err)
+		}
+		if len(interfaces) == 0 {
+			return fmt.Errorf("Instance %s has no interfaces", inst.UUID)
+		}
+		if err := apiClient.FloatInterface(interfaces[0].UUID); err != nil {
+			return fmt.Errorf("Unable to float instance interface: %v", err)
+		}
+		return nil
+	}
+}
+
+func testAccCheckNamespaceDestroy(s *terraform.State) error {
+	apiClient := testAccProvider.Meta().(*providerMeta).client
+
+	names, err := apiClient.GetNamespaces()
+	if err != nil {
+		return fmt.Errorf("Namespaces cannot be retrieved: %v", err)
+	}
+
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "shakenfist_namespace" {
+			continue
+		}
+		for _, name := range names {
+			if name == rs.Primary.ID {
+				return fmt.Errorf("Namespace %s still exists", name)
+			}
+		}
+	}
+
+	return nil
+}
+
 func testAccSystemKey() (bool, error) {
 	namespace := os.Getenv("SHAKENFIST_NAMESPACE")
 
@@ -99,7 +221,7 @@ func testAccNamespaceMetadata(
 		}
 
 		// Retrieve the configured instance from the test setup
-		apiClient := testAccProvider.Meta().(*client.Client)
+		apiClient := testAccProvider.Meta().(*providerMeta).client
 		serverMeta, err := apiClient.GetNamespaceMetadata(rs.Primary.ID)
 		if err != nil {
 			return fmt.Errorf("Instance (%s) metadata cannot be retrieved: %v",
@@ -146,7 +268,7 @@ func testAccCheckNamespaceExists(
 		}
 
 		// Retrieve the configured namespace from the test setup
-		apiClient := testAccProvider.Meta().(*client.Client)
+		apiClient := testAccProvider.Meta().(*providerMeta).client
 		names, err := apiClient.GetNamespaces()
 		if err != nil {
 			return fmt.Errorf("Namespaces cannot be retrieved: %v", err)
@@ -3,34 +3,41 @@
 package provider
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"regexp"
-	"strings"
 	"time"
 
-	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
-	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	client "github.com/shakenfist/client-go"
 )
 
-func validateNetblock(v interface{}, k string) ([]string, []error) {
-	var errs []error
-	var warns []string
-
+func validateNetblock(v interface{}, path cty.Path) diag.Diagnostics {
 	value, ok := v.(string)
 	if !ok {
-		errs = append(errs, fmt.Errorf("Expected netblock to be a string"))
-		return warns, errs
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "Expected netblock to be a string",
+			AttributePath: path,
+		}}
 	}
 
-	netblock := regexp.MustCompile(
+	re := regexp.MustCompile(
 		`^[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}/[0-9]{1,3}$`)
-	if !netblock.Match([]byte(value)) {
-		errs = append(errs,
-			fmt.Errorf("Netblock must be IPv4 CIDR. Got %s", value))
-		return warns, errs
+	if !re.Match([]byte(value)) {
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "Netblock must be an IPv4 CIDR",
+			Detail:        fmt.Sprintf("Got %s", value),
+			AttributePath: path,
+		}}
 	}
-	return warns, errs
+
+	return nil
 }
 
 func resourceNetwork() *schema.Resource {
@@ -43,17 +50,25 @@ func resourceNetwork() *schema.Resource {
 				Description: "The name of the network",
 				ForceNew:    true,
 			},
+			"namespace": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+				Description: "Namespace of the network, " +
+					"by default the provider namespace",
+			},
 			"uuid": {
 				Type:        schema.TypeString,
 				Computed:    true,
 				Description: "The UUID of the network",
 			},
 			"netblock": {
-				Type:         schema.TypeString,
-				Required:     true,
-				Description:  "The CIDR IP range of the network",
-				ForceNew:     true,
-				ValidateFunc: validateNetblock,
+				Type:             schema.TypeString,
+				Required:         true,
+				Description:      "The CIDR IP range of the network",
+				ForceNew:         true,
+				ValidateDiagFunc: validateNetblock,
 			},
 			"provide_dhcp": {
 				Type:        schema.TypeBool,
@@ -67,20 +82,26 @@ func resourceNetwork() *schema.Resource {
 				Description: "Should NAT services exist on the network?",
 				ForceNew:    true,
 			},
+			"state": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "State of the network",
+			},
 			"metadata": {
 				Type:     schema.TypeMap,
 				Optional: true,
+				Description: "Metadata of the network, " +
+					"other keys are removed",
 				Elem: &schema.Schema{
 					Type: schema.TypeString},
 			},
 		},
-		Create: resourceCreateNetwork,
-		Read:   resourceReadNetwork,
-		Delete: resourceDeleteNetwork,
-		Exists: resourceExistsNetwork,
-		Update: resourceUpdateNetwork,
+		CreateContext: resourceCreateNetwork,
+		ReadContext:   resourceReadNetwork,
+		DeleteContext: resourceDeleteNetwork,
+		UpdateContext: resourceUpdateNetwork,
 		Importer: &schema.ResourceImporter{
-			State: schema.ImportStatePassthrough,
+			StateContext: schema.ImportStatePassthroughContext,
 		},
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(5 * time.Minute),
@@ -89,18 +110,30 @@ func resourceNetwork() *schema.Resource {
 	}
 }
 
-func resourceCreateNetwork(d *schema.ResourceData, m interface{}) error {
-	apiClient := m.(*client.Client)
+func resourceCreateNetwork(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
+
+	namespace, err := m.(*providerMeta).objectNamespace(d)
+	if err != nil {
+		return diag.Errorf("Unable to create network: %v", err)
+	}
 
-	network, err := apiClient.CreateNetwork(
-		d.Get("netblock").(string), d.Get("provide_dhcp").(bool),
-		d.Get("provide_nat").(bool), d.Get("name").(string))
+	createClient, err := m.(*providerMeta).createClient(
+		createOptions{namespace: namespace})
+	if err != nil {
+		return diag.Errorf("Unable to create network: %v", err)
+	}
+	network, err := createClient.CreateNetwork(d.Get("netblock").(string),
+		d.Get("provide_dhcp").(bool), d.Get("provide_nat").(bool),
+		d.Get("name").(string))
 	if err != nil {
-		return fmt.Errorf("Unable to create network: %v", err)
+		return diag.Errorf("Unable to create network: %v", err)
 	}
 
 	if err := d.Set("uuid", network.UUID); err != nil {
-		return fmt.Errorf("UUID cannot be set: %v", err)
+		return diag.Errorf("UUID cannot be set: %v", err)
 	}
 
 	d.SetId(network.UUID)
@@ -109,26 +142,26 @@ func resourceCreateNetwork(d *schema.ResourceData, m interface{}) error {
 	for k, v := range d.Get("metadata").(map[string]interface{}) {
 		val, ok := v.(string)
 		if !ok {
-			return fmt.Errorf("Tag value is not a string")
+			return diag.Errorf("Tag value is not a string")
 		}
 
 		err := apiClient.SetMetadata(client.TypeNetwork, network.UUID, k, val)
 		if err != nil {
-			return fmt.Errorf("CreateNetwork cannot store metadata: %v", err)
+			return diag.Errorf("CreateNetwork cannot store metadata: %v", err)
 		}
 	}
 
-	return resource.Retry(d.Timeout(schema.TimeoutCreate),
+	err = resource.RetryContext(ctx, d.Timeout(schema.TimeoutCreate),
 		func() *resource.RetryError {
 
 			i, err := apiClient.GetNetwork(d.Id())
 			if err != nil {
-				if strings.Contains(err.Error(), "not found") {
-					return resource.NonRetryableError(nil)
-				} else {
-					return resource.NonRetryableError(fmt.Errorf(
-						"Unable to check network existence: %v", err))
+				if isNotFound(err) {
+					// The network may not be visible immediately.
+					return resource.RetryableError(fmt.Errorf(
+						"network not found"))
 				}
+				return retryError(err, "Unable to check network existence")
 			}
 
 			if i.State == "error" {
@@ -140,19 +173,61 @@ func resourceCreateNetwork(d *schema.ResourceData, m interface{}) error {
 					"network not created"))
 			}
 
-			return resource.NonRetryableError(resourceReadNetwork(d, m))
+			network = i
+			return nil
 		},
 	)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if namespace == "" {
+		namespace = m.(*providerMeta).namespace
+	}
+	if network.Namespace != namespace {
+		return diag.Errorf("Shaken Fist did not honour the network "+
+			"namespace, the network was requested in namespace %s but is "+
+			"in namespace %s", namespace, network.Namespace)
+	}
+
+	return resourceReadNetwork(ctx, d, m)
 }
 
-func resourceReadNetwork(d *schema.ResourceData, m interface{}) error {
-	apiClient := m.(*client.Client)
+func resourceReadNetwork(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
 
 	network, err := apiClient.GetNetwork(d.Id())
 	if err != nil {
-		return fmt.Errorf("Unable to retrieve network: %v", err)
+		if isNotFound(err) {
+			log.Printf("[WARN] Network %s not found, removing from state",
+				d.Id())
+			d.SetId("")
+			return nil
+		}
+		return diag.Errorf("Unable to retrieve network: %v", err)
+	}
+
+	if network.State == "deleted" {
+		log.Printf("[WARN] Network %s is deleted, removing from state", d.Id())
+		d.SetId("")
+		return nil
 	}
 
+	if err := d.Set("namespace", network.Namespace); err != nil {
+		return diag.Errorf("Network Namespace cannot be set: %v", err)
+	}
+
+	return diag.FromErr(setNetworkData(d, apiClient, network))
+}
+
+// setNetworkData sets the Terraform attributes from the Shaken Fist network,
+// including its metadata. It is shared by the network resource and data
+// sources.
+func setNetworkData(d *schema.ResourceData, apiClient *client.Client,
+	network client.Network) error {
+
 	if err := d.Set("uuid", network.UUID); err != nil {
 		return fmt.Errorf("Network UUID cannot be set: %v", err)
 	}
@@ -168,9 +243,12 @@ func resourceReadNetwork(d *schema.ResourceData, m interface{}) error {
 	if err := d.Set("provide_nat", network.ProvideNAT); err != nil {
 		return fmt.Errorf("Network ProvideNAT flag cannot be set: %v", err)
 	}
+	if err := d.Set("state", network.State); err != nil {
+		return fmt.Errorf("Network State cannot be set: %v", err)
+	}
 
 	// Retrieve metadata
-	metadata, err := apiClient.GetNetworkMetadata(d.Id())
+	metadata, err := apiClient.GetNetworkMetadata(network.UUID)
 	if err != nil {
 		return fmt.Errorf("ReadNetwork unable to retrieve metadata: %v", err)
 	}
@@ -181,25 +259,30 @@ func resourceReadNetwork(d *schema.ResourceData, m interface{}) error {
 	return nil
 }
 
-func resourceDeleteNetwork(d *schema.ResourceData, m interface{}) error {
-	apiClient := m.(*client.Client)
+func resourceDeleteNetwork(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
 
 	err := apiClient.DeleteNetwork(d.Id())
 	if err != nil {
-		return fmt.Errorf("Unable to delete network: %v", err)
+		if isNotFound(err) {
+			d.SetId("")
+			return nil
+		}
+		return diag.Errorf("Unable to delete network: %v", err)
 	}
 
-	return resource.Retry(d.Timeout(schema.TimeoutDelete),
+	err = resource.RetryContext(ctx, d.Timeout(schema.TimeoutDelete),
 		func() *resource.RetryError {
 
 			i, err := apiClient.GetNetwork(d.Id())
 			if err != nil {
-				if strings.Contains(err.Error(), "not found") {
-					return resource.NonRetryableError(nil)
-				} else {
-					return resource.NonRetryableError(fmt.Errorf(
-						"Unable to check network existence: %v", err))
+				if isNotFound(err) {
+					d.SetId("")
+					return nil
 				}
+				return retryError(err, "Unable to check network existence")
 			}
 
 			if i.State == "error" {
@@ -213,38 +296,23 @@ func resourceDeleteNetwork(d *schema.ResourceData, m interface{}) error {
 
 			d.SetId("")
 
-			return resource.NonRetryableError(nil)
+			return nil
 		},
 	)
-}
-
-func resourceExistsNetwork(d *schema.ResourceData, m interface{}) (bool, error) {
-	apiClient := m.(*client.Client)
 
-	n, err := apiClient.GetNetwork(d.Id())
-	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			return false, nil
-		} else {
-			return false, fmt.Errorf("Unable to check network existence: %v", err)
-		}
-	}
-
-	if n.State == "deleted" {
-		return false, nil
-	}
-
-	return true, nil
+	return diag.FromErr(err)
 }
 
-func resourceUpdateNetwork(d *schema.ResourceData, m interface{}) error {
-	apiClient := m.(*client.Client)
+func resourceUpdateNetwork(ctx context.Context, d *schema.ResourceData,
+	m interface{}) diag.Diagnostics {
+
+	apiClient := m.(*providerMeta).client
 
 	if d.HasChange("metadata") {
 		if err := updateMetadata(client.TypeNetwork, d, apiClient); err != nil {
-			return fmt.Errorf("UpdateNetwork error: %v", err)
+			return diag.Errorf("UpdateNetwork error: %v", err)
 		}
 	}
 
-	return nil
+	return resourceReadNetwork(ctx, d, m)
 }
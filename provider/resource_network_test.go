@@ -2,13 +2,15 @@ package provider
 
 import (
 	"fmt"
+	"net/http"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
-	"github.com/hashicorp/terraform-plugin-sdk/helper/acctest"
-	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
-	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 	client "github.com/shakenfist/client-go"
 )
 
@@ -66,6 +68,61 @@ func TestAccShakenFistNetwork(t *testing.T) {
 	})
 }
 
+// TestAccShakenFistNetworkDisappears tests that a network deleted outside of
+// Terraform is removed from the state and planned for recreation.
+func TestAccShakenFistNetworkDisappears(t *testing.T) {
+	var network client.Network
+
+	randomName := acctest.RandStringFromCharSet(10, acctest.CharSetAlphaNum)
+
+	resName := "shakenfist_network.external"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceNetwork1(randomName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNetworkExists(resName, &network),
+					testAccNetworkDisappears(&network),
+				),
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
+// TestAccShakenFistNetworkAPIErrors tests that transient failures of the
+// Shaken Fist API are retried. It only runs against the fake API.
+func TestAccShakenFistNetworkAPIErrors(t *testing.T) {
+	var network client.Network
+
+	randomName := acctest.RandStringFromCharSet(10, acctest.CharSetAlphaNum)
+
+	resName := "shakenfist_network.external"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheckMock(t)
+			testAccMockServer.failRequests(
+				"GET", "/networks/", http.StatusServiceUnavailable, 2)
+			testAccMockServer.failRequests(
+				"DELETE", "/networks/", http.StatusBadGateway, 1)
+		},
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceNetwork1(randomName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNetworkExists(resName, &network),
+					resource.TestCheckResourceAttr(resName, "state", "created"),
+				),
+			},
+		},
+	})
+}
+
 func testAccResourceNetwork1(randomName string) string {
 	res := `
 	resource "shakenfist_network" "external" {
@@ -155,7 +212,7 @@ func testAccNetworkMetadata(
 		}
 
 		// Retrieve the configured instance from the test setup
-		apiClient := testAccProvider.Meta().(*client.Client)
+		apiClient := testAccProvider.Meta().(*providerMeta).client
 		serverMeta, err := apiClient.GetNetworkMetadata(rs.Primary.ID)
 		if err != nil {
 			return fmt.Errorf("Instance (%s) metadata cannot be retrieved: %v",
@@ -175,7 +232,7 @@ func testAccCheckNetworkExists(n string, net *client.Network) resource.TestCheck
 		}
 
 		// Retrieve the configured network from the test setup
-		apiClient := testAccProvider.Meta().(*client.Client)
+		apiClient := testAccProvider.Meta().(*providerMeta).client
 		resp, err := apiClient.GetNetwork(rs.Primary.ID)
 		if err != nil {
 			return fmt.Errorf("Network (%s) cannot be retrieved: %v", rs.Primary.ID, err)
@@ -186,3 +243,29 @@ func testAccCheckNetworkExists(n string, net *client.Network) resource.TestCheck
 		return nil
 	}
 }
+
+// testAccNetworkDisappears deletes the network directly via the API.
+func testAccNetworkDisappears(net *client.Network) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		apiClient := testAccProvider.Meta().(*providerMeta).client
+		if err := apiClient.DeleteNetwork(net.UUID); err != nil {
+			return fmt.Errorf("Network (%s) cannot be deleted: %v",
+				net.UUID, err)
+		}
+
+		return resource.Retry(time.Minute, func() *resource.RetryError {
+			n, err := apiClient.GetNetwork(net.UUID)
+			if err != nil {
+				if isNotFound(err) {
+					return nil
+				}
+				return resource.NonRetryableError(err)
+			}
+			if n.State != "deleted" {
+				return resource.RetryableError(fmt.Errorf(
+					"network not deleted"))
+			}
+			return nil
+		})
+	}
+}
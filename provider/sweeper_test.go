@@ -0,0 +1,295 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	client "github.com/shakenfist/client-go"
+)
+
+// sweepPrefix is the name prefix of all objects created by the acceptance
+// tests. Objects with the prefix are deleted by the sweepers.
+const sweepPrefix = "testacc-"
+
+// sweepTimeout is how long a sweeper waits for objects to be deleted.
+const sweepTimeout = 10 * time.Minute
+
+// Sweepers run in dependency order: floating IPs are removed from instances,
+// instances are deleted before the networks they use, and keys and
+// namespaces are deleted last.
+func init() {
+	resource.AddTestSweepers("shakenfist_float", &resource.Sweeper{
+		Name: "shakenfist_float",
+		F:    sweepFloats,
+	})
+
+	resource.AddTestSweepers("shakenfist_instance", &resource.Sweeper{
+		Name:         "shakenfist_instance",
+		F:            sweepInstances,
+		Dependencies: []string{"shakenfist_float"},
+	})
+
+	resource.AddTestSweepers("shakenfist_network", &resource.Sweeper{
+		Name:         "shakenfist_network",
+		F:            sweepNetworks,
+		Dependencies: []string{"shakenfist_instance"},
+	})
+
+	resource.AddTestSweepers("shakenfist_key", &resource.Sweeper{
+		Name: "shakenfist_key",
+		F:    sweepKeys,
+	})
+
+	resource.AddTestSweepers("shakenfist_namespace", &resource.Sweeper{
+		Name: "shakenfist_namespace",
+		F:    sweepNamespaces,
+		Dependencies: []string{
+			"shakenfist_instance",
+			"shakenfist_network",
+			"shakenfist_key",
+		},
+	})
+}
+
+// sweepClient returns an API client configured from the provider environment
+// variables or client config file. Shaken Fist has no regions, so the sweep
+// region is ignored.
+func sweepClient() (*client.Client, error) {
+	p := Provider()
+	diags := p.Configure(context.Background(),
+		terraform.NewResourceConfigRaw(nil))
+	if diags.HasError() {
+		return nil, fmt.Errorf("Unable to configure provider: %s",
+			diags[0].Summary)
+	}
+
+	return p.Meta().(*providerMeta).client, nil
+}
+
+// sweepableInstances returns the instances created by the acceptance tests
+// which have not been deleted.
+func sweepableInstances(apiClient *client.Client) ([]client.Instance, error) {
+	instances, err := apiClient.GetInstances()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve instances: %v", err)
+	}
+
+	var sweepable []client.Instance
+	for _, inst := range instances {
+		if strings.HasPrefix(inst.Name, sweepPrefix) &&
+			inst.State != "deleted" {
+			sweepable = append(sweepable, inst)
+		}
+	}
+	return sweepable, nil
+}
+
+func sweepFloats(region string) error {
+	apiClient, err := sweepClient()
+	if err != nil {
+		return err
+	}
+
+	instances, err := sweepableInstances(apiClient)
+	if err != nil {
+		return err
+	}
+
+	for _, inst := range instances {
+		interfaces, err := apiClient.GetInstanceInterfaces(inst.UUID)
+		if err != nil {
+			if isNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("Unable to retrieve interfaces of instance %s: %v",
+				inst.UUID, err)
+		}
+
+		for _, iface := range interfaces {
+			if iface.Floating == "" {
+				continue
+			}
+
+			log.Printf("[INFO] Removing floating IP from interface %s of "+
+				"instance %s", iface.UUID, inst.Name)
+			err := apiClient.DefloatInterface(iface.UUID)
+			if err != nil && !isNotFound(err) {
+				return fmt.Errorf("Unable to remove floating IP from "+
+					"interface %s: %v", iface.UUID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func sweepInstances(region string) error {
+	apiClient, err := sweepClient()
+	if err != nil {
+		return err
+	}
+
+	instances, err := sweepableInstances(apiClient)
+	if err != nil {
+		return err
+	}
+
+	for _, inst := range instances {
+		log.Printf("[INFO] Deleting instance %s (%s)", inst.Name, inst.UUID)
+		err := apiClient.DeleteInstance(inst.UUID)
+		if err != nil && !isNotFound(err) {
+			return fmt.Errorf("Unable to delete instance %s: %v",
+				inst.UUID, err)
+		}
+	}
+
+	// Networks cannot be deleted until their instances are gone
+	for _, inst := range instances {
+		err := resource.Retry(sweepTimeout, func() *resource.RetryError {
+			i, err := apiClient.GetInstance(inst.UUID)
+			if err != nil {
+				if isNotFound(err) {
+					return nil
+				}
+				return retryError(err, "Unable to check instance existence")
+			}
+			if i.State != "deleted" {
+				return resource.RetryableError(fmt.Errorf(
+					"instance %s not deleted", inst.UUID))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sweepNetworks(region string) error {
+	apiClient, err := sweepClient()
+	if err != nil {
+		return err
+	}
+
+	networks, err := apiClient.GetNetworks()
+	if err != nil {
+		return fmt.Errorf("Unable to retrieve networks: %v", err)
+	}
+
+	var deleted []string
+	for _, n := range networks {
+		if !strings.HasPrefix(n.Name, sweepPrefix) || n.State == "deleted" {
+			continue
+		}
+
+		log.Printf("[INFO] Deleting network %s (%s)", n.Name, n.UUID)
+		err := apiClient.DeleteNetwork(n.UUID)
+		if err != nil && !isNotFound(err) {
+			return fmt.Errorf("Unable to delete network %s: %v", n.UUID, err)
+		}
+		deleted = append(deleted, n.UUID)
+	}
+
+	for _, uuid := range deleted {
+		err := resource.Retry(sweepTimeout, func() *resource.RetryError {
+			n, err := apiClient.GetNetwork(uuid)
+			if err != nil {
+				if isNotFound(err) {
+					return nil
+				}
+				return retryError(err, "Unable to check network existence")
+			}
+			if n.State != "deleted" {
+				return resource.RetryableError(fmt.Errorf(
+					"network %s not deleted", uuid))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sweepableNamespaces returns the namespaces created by the acceptance tests.
+func sweepableNamespaces(apiClient *client.Client) ([]string, error) {
+	namespaces, err := apiClient.GetNamespaces()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve namespaces: %v", err)
+	}
+
+	var sweepable []string
+	for _, name := range namespaces {
+		if strings.HasPrefix(name, sweepPrefix) {
+			sweepable = append(sweepable, name)
+		}
+	}
+	return sweepable, nil
+}
+
+func sweepKeys(region string) error {
+	apiClient, err := sweepClient()
+	if err != nil {
+		return err
+	}
+
+	namespaces, err := sweepableNamespaces(apiClient)
+	if err != nil {
+		return err
+	}
+
+	for _, namespace := range namespaces {
+		keynames, err := apiClient.GetNamespaceKeys(namespace)
+		if err != nil {
+			if isNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("Unable to retrieve keys of namespace %s: %v",
+				namespace, err)
+		}
+
+		for _, keyname := range keynames {
+			log.Printf("[INFO] Deleting key %s of namespace %s",
+				keyname, namespace)
+			err := apiClient.DeleteNamespaceKey(namespace, keyname)
+			if err != nil && !isNotFound(err) {
+				return fmt.Errorf("Unable to delete key %s of namespace "+
+					"%s: %v", keyname, namespace, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func sweepNamespaces(region string) error {
+	apiClient, err := sweepClient()
+	if err != nil {
+		return err
+	}
+
+	namespaces, err := sweepableNamespaces(apiClient)
+	if err != nil {
+		return err
+	}
+
+	for _, namespace := range namespaces {
+		log.Printf("[INFO] Deleting namespace %s", namespace)
+		err := apiClient.DeleteNamespace(namespace)
+		if err != nil && !isNotFound(err) {
+			return fmt.Errorf("Unable to delete namespace %s: %v",
+				namespace, err)
+		}
+	}
+
+	return nil
+}
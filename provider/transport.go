@@ -0,0 +1,222 @@
+package provider
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// The Shaken Fist client library does not accept a custom HTTP client, its
+// requests use http.DefaultTransport. The provider therefore replaces the
+// default transport with hostTransport, which sends requests for a configured
+// Shaken Fist API server through the transport built for that server.
+var (
+	baseTransport = http.DefaultTransport.(*http.Transport)
+
+	installTransport sync.Once
+	apiTransports    = &hostTransport{
+		hosts:    map[string]*apiServer{},
+		fallback: baseTransport,
+	}
+)
+
+// apiSettings are the provider arguments configuring the transport to a
+// Shaken Fist API server.
+type apiSettings struct {
+	tls         tlsSettings
+	retry       retryPolicy
+	requestRate float64
+	maxInFlight int
+}
+
+// apiServer is the transport registered for a Shaken Fist API server, with
+// the settings it was built from.
+type apiServer struct {
+	settings  apiSettings
+	transport http.RoundTripper
+}
+
+// hostTransport dispatches requests to a transport chosen by the URL host.
+type hostTransport struct {
+	lock     sync.RWMutex
+	hosts    map[string]*apiServer
+	fallback http.RoundTripper
+}
+
+func (t *hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.lock.RLock()
+	server, ok := t.hosts[req.URL.Host]
+	t.lock.RUnlock()
+
+	if !ok {
+		return t.fallback.RoundTrip(req)
+	}
+	return server.transport.RoundTrip(req)
+}
+
+// registerAPITransport sends all HTTP requests to the Shaken Fist API server
+// via a transport built from the settings. Requests cannot be told apart by
+// provider configuration, so configurations for the same server share the
+// transport and its request limits, and must have the same settings.
+func registerAPITransport(serverURL string, settings apiSettings,
+	logBodies bool) error {
+
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return fmt.Errorf("Server URL is invalid: %v", err)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("Server URL has no host: %s", serverURL)
+	}
+
+	apiTransports.lock.Lock()
+	defer apiTransports.lock.Unlock()
+
+	if server, ok := apiTransports.hosts[u.Host]; ok {
+		if server.settings != settings {
+			return fmt.Errorf("Provider configurations for server %s "+
+				"have different TLS, retry or request limit settings, "+
+				"these must be the same for all configurations of a server",
+				u.Host)
+		}
+		return nil
+	}
+
+	tlsConf, err := newTLSConfig(settings.tls)
+	if err != nil {
+		return fmt.Errorf("TLS configuration error: %v", err)
+	}
+
+	apiTransports.hosts[u.Host] = &apiServer{
+		settings: settings,
+		transport: newAPITransport(apiTransportConfig{
+			tls:   tlsConf,
+			retry: settings.retry,
+			limiter: newRequestLimiter(settings.requestRate,
+				settings.maxInFlight),
+			logBodies: logBodies,
+		}),
+	}
+
+	installTransport.Do(func() {
+		http.DefaultTransport = apiTransports
+	})
+
+	return nil
+}
+
+// tlsSettings holds the provider TLS arguments.
+type tlsSettings struct {
+	caCertFile string
+	caCertPEM  string
+	clientCert string
+	clientKey  string
+	insecure   bool
+}
+
+// newTLSConfig builds the TLS configuration used to connect to the Shaken Fist
+// API. The client certificate and key can be PEM encoded data or file paths.
+func newTLSConfig(s tlsSettings) (*tls.Config, error) {
+	conf := &tls.Config{
+		InsecureSkipVerify: s.insecure,
+	}
+
+	caPEM := []byte(s.caCertPEM)
+	if s.caCertFile != "" {
+		data, err := ioutil.ReadFile(s.caCertFile)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read CA certificate file: %v",
+				err)
+		}
+		caPEM = append(caPEM, '\n')
+		caPEM = append(caPEM, data...)
+	}
+	if strings.TrimSpace(string(caPEM)) != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("No valid CA certificates found")
+		}
+		conf.RootCAs = pool
+	}
+
+	if s.clientCert != "" || s.clientKey != "" {
+		if s.clientCert == "" || s.clientKey == "" {
+			return nil, fmt.Errorf(
+				"Both client_cert and client_key must be set")
+		}
+
+		certPEM, err := pemOrFile(s.clientCert)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read client certificate: %v",
+				err)
+		}
+		keyPEM, err := pemOrFile(s.clientKey)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read client key: %v", err)
+		}
+
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid client certificate: %v", err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	return conf, nil
+}
+
+// pemOrFile returns the value if it is PEM encoded data, otherwise the
+// contents of the file named by the value.
+func pemOrFile(value string) ([]byte, error) {
+	if strings.HasPrefix(strings.TrimSpace(value), "-----BEGIN") {
+		return []byte(value), nil
+	}
+	return ioutil.ReadFile(value)
+}
+
+// apiTransportConfig holds the settings of the HTTP transport used for
+// requests to the Shaken Fist API.
+type apiTransportConfig struct {
+	tls       *tls.Config
+	retry     retryPolicy
+	limiter   *requestLimiter
+	logBodies bool
+}
+
+// newAPITransport returns the HTTP transport used for requests to the Shaken
+// Fist API. Each retry of a request is subject to the limiter and is logged,
+// and failed responses are returned as classified errors.
+// Creation requests made by a client returned by createURL carry its
+// namespace and node.
+func newAPITransport(conf apiTransportConfig) http.RoundTripper {
+	t := baseTransport.Clone()
+	t.TLSClientConfig = conf.tls
+
+	var rt http.RoundTripper = &logTransport{
+		next:      t,
+		logBodies: conf.logBodies,
+	}
+	if conf.limiter != nil {
+		rt = &limitTransport{
+			limiter: conf.limiter,
+			next:    rt,
+		}
+	}
+
+	return &createTransport{
+		next: &statusTransport{
+			next: &retryTransport{
+				policy: conf.retry,
+				next:   rt,
+			},
+		},
+	}
+}
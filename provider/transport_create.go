@@ -0,0 +1,135 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// The Shaken Fist client library cannot request a namespace when creating an
+// instance or network, nor a node when creating an instance or caching an
+// image. Such objects are therefore created with a client whose server URL
+// carries the options as its user, so that each request names its own
+// namespace and node, and createTransport moves them into the request.
+
+// createOptions are the options of a creation request the client library
+// cannot send. An empty option is not sent.
+type createOptions struct {
+	namespace string
+	node      string
+}
+
+// createField is a request body field set from a creation option: the path
+// POSTed to, the option and the body field.
+type createField struct {
+	path   string
+	option string
+	field  string
+}
+
+// createFields are the creation requests that carry options.
+var createFields = []createField{
+	{"/instances", "namespace", "namespace"},
+	{"/instances", "node", "placed_on"},
+	{"/networks", "namespace", "namespace"},
+	{"/images", "node", "node"},
+}
+
+// createURL returns the server URL used by a client creating objects with
+// the options.
+func createURL(serverURL string, opts createOptions) (string, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return "", fmt.Errorf("Invalid server URL %s: %v", serverURL, err)
+	}
+
+	values := url.Values{}
+	if opts.namespace != "" {
+		values.Set("namespace", opts.namespace)
+	}
+	if opts.node != "" {
+		values.Set("node", opts.node)
+	}
+	u.User = url.User(values.Encode())
+	return u.String(), nil
+}
+
+// createTransport removes the creation options from the URL of requests made
+// by a client returned by createURL, and adds them to the creation requests
+// they apply to.
+type createTransport struct {
+	next http.RoundTripper
+}
+
+func (t *createTransport) RoundTrip(req *http.Request) (*http.Response,
+	error) {
+
+	if req.URL.User == nil {
+		return t.next.RoundTrip(req)
+	}
+	options, err := url.ParseQuery(req.URL.User.Username())
+	if err != nil {
+		return nil, fmt.Errorf("Invalid creation options: %v", err)
+	}
+
+	// The HTTP client sends the URL user as basic authentication, which the
+	// Shaken Fist API does not use
+	req = req.Clone(req.Context())
+	req.URL.User = nil
+	if strings.HasPrefix(req.Header.Get("Authorization"), "Basic ") {
+		req.Header.Del("Authorization")
+	}
+
+	if req.Method != http.MethodPost || req.GetBody == nil {
+		return t.next.RoundTrip(req)
+	}
+
+	path := strings.TrimSuffix(req.URL.Path, "/")
+	fields := map[string]string{}
+	for _, f := range createFields {
+		if strings.HasSuffix(path, f.path) && options.Get(f.option) != "" {
+			fields[f.field] = options.Get(f.option)
+		}
+	}
+	if len(fields) == 0 {
+		return t.next.RoundTrip(req)
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to add options to request: %v", err)
+	}
+	data, err := ioutil.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to add options to request: %v", err)
+	}
+
+	var create map[string]interface{}
+	if err := json.Unmarshal(data, &create); err != nil {
+		return nil, fmt.Errorf("Unable to add options to request: %v", err)
+	}
+	for field, value := range fields {
+		create[field] = value
+	}
+	data, err = json.Marshal(create)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to add options to request: %v", err)
+	}
+
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+	req.ContentLength = int64(len(data))
+
+	return t.next.RoundTrip(req)
+}
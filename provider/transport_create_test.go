@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUnitCreateTransport(t *testing.T) {
+	var body map[string]interface{}
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			body = nil
+			json.NewDecoder(r.Body).Decode(&body)
+			authorization = r.Header.Get("Authorization")
+		}))
+	defer server.Close()
+
+	c := &http.Client{Transport: &createTransport{
+		next: baseTransport.Clone(),
+	}}
+
+	post := func(opts createOptions, path string) {
+		serverURL := server.URL
+		if opts != (createOptions{}) {
+			var err error
+			serverURL, err = createURL(server.URL, opts)
+			if err != nil {
+				t.Fatalf("Unable to build creation URL: %v", err)
+			}
+		}
+		resp, err := c.Post(serverURL+path, "application/json",
+			strings.NewReader(`{"name": "web-1", "cpus": 1}`))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	post(createOptions{}, "/instances")
+	if body["namespace"] != nil || body["placed_on"] != nil {
+		t.Errorf("Instance without options created with %v", body)
+	}
+
+	// Objects of the same name are created with the options of each client
+	post(createOptions{namespace: "lab123"}, "/instances")
+	if body["namespace"] != "lab123" || body["placed_on"] != nil {
+		t.Errorf("Instance created with %v, expected lab123", body)
+	}
+	post(createOptions{namespace: "lab456", node: "sf-2"}, "/instances")
+	if body["namespace"] != "lab456" || body["placed_on"] != "sf-2" {
+		t.Errorf("Instance created with %v, expected lab456 on sf-2", body)
+	}
+	post(createOptions{namespace: "lab123", node: "sf-2"}, "/networks")
+	if body["namespace"] != "lab123" || body["placed_on"] != nil {
+		t.Errorf("Network created with %v, expected lab123", body)
+	}
+	post(createOptions{node: "sf-3"}, "/images")
+	if body["node"] != "sf-3" || body["namespace"] != nil {
+		t.Errorf("Image cached with %v, expected sf-3", body)
+	}
+	if authorization != "" {
+		t.Errorf("Options sent as authorization %v", authorization)
+	}
+
+	post(createOptions{namespace: "lab123"}, "/auth")
+	if body["namespace"] != nil {
+		t.Errorf("Authentication request sent namespace %v",
+			body["namespace"])
+	}
+}
@@ -0,0 +1,115 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// requestLimiter limits the rate of requests to the Shaken Fist API and the
+// number of requests in flight at once. It is shared by all resources using
+// the provider configuration.
+type requestLimiter struct {
+	interval time.Duration
+	inFlight chan struct{}
+
+	lock sync.Mutex
+	next time.Time
+}
+
+// newRequestLimiter returns a limiter allowing requestsPerSecond requests per
+// second with at most maxInFlight requests in flight. Zero disables either
+// limit.
+func newRequestLimiter(requestsPerSecond float64,
+	maxInFlight int) *requestLimiter {
+
+	l := &requestLimiter{}
+	if requestsPerSecond > 0 {
+		l.interval = time.Duration(float64(time.Second) / requestsPerSecond)
+	}
+	if maxInFlight > 0 {
+		l.inFlight = make(chan struct{}, maxInFlight)
+	}
+	return l
+}
+
+// acquire waits until a request can be made. Every successful acquire must be
+// followed by a release.
+func (l *requestLimiter) acquire(ctx context.Context) error {
+	if l.inFlight != nil {
+		select {
+		case l.inFlight <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if l.interval > 0 {
+		l.lock.Lock()
+		now := time.Now()
+		if l.next.Before(now) {
+			l.next = now
+		}
+		wait := l.next.Sub(now)
+		l.next = l.next.Add(l.interval)
+		l.lock.Unlock()
+
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				l.release()
+				return ctx.Err()
+			}
+		}
+	}
+
+	return nil
+}
+
+// release marks a request as no longer in flight.
+func (l *requestLimiter) release() {
+	if l.inFlight != nil {
+		<-l.inFlight
+	}
+}
+
+// limitTransport applies the request limiter to each HTTP request. A request
+// is in flight until its response body is closed.
+type limitTransport struct {
+	limiter *requestLimiter
+	next    http.RoundTripper
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.acquire(req.Context()); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.limiter.release()
+		return nil, err
+	}
+
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: t.limiter.release}
+	return resp, nil
+}
+
+// releaseBody calls release once when the response body is closed.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,83 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUnitLimitTransportConcurrency(t *testing.T) {
+	var inFlight, maxSeen int32
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				m := atomic.LoadInt32(&maxSeen)
+				if n <= m || atomic.CompareAndSwapInt32(&maxSeen, m, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+		}))
+	defer server.Close()
+
+	c := &http.Client{Transport: &limitTransport{
+		limiter: newRequestLimiter(0, 2),
+		next:    baseTransport.Clone(),
+	}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := c.Get(server.URL)
+			if err != nil {
+				t.Errorf("Request failed: %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	if maxSeen > 2 {
+		t.Errorf("%d requests in flight, limit is 2", maxSeen)
+	}
+}
+
+func TestUnitRequestLimiterRate(t *testing.T) {
+	l := newRequestLimiter(100, 0)
+
+	start := time.Now()
+	for i := 0; i < 11; i++ {
+		if err := l.acquire(context.Background()); err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+		l.release()
+	}
+
+	// The first request is immediate, the following ten are 10ms apart
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("11 requests at 100/s took only %v", elapsed)
+	}
+}
+
+func TestUnitRequestLimiterCancel(t *testing.T) {
+	l := newRequestLimiter(0, 1)
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(ctx); err == nil {
+		t.Errorf("Acquire beyond the limit should wait for cancellation")
+	}
+}
@@ -0,0 +1,139 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// correlationHeader is the HTTP header identifying a request in the provider
+// log, and in the Shaken Fist API server log if it records the header.
+const correlationHeader = "X-Request-ID"
+
+// maxLoggedBody is the largest request or response body written to the log.
+const maxLoggedBody = 16 * 1024
+
+// redactedFields are JSON fields whose values are never logged.
+var redactedFields = map[string]bool{
+	"key":          true,
+	"access_token": true,
+	"user_data":    true,
+}
+
+// logTransport logs each request to the Shaken Fist API and its response via
+// the Terraform log. The request summary is logged at DEBUG level, the request
+// and response bodies at TRACE level with secrets redacted.
+type logTransport struct {
+	next http.RoundTripper
+
+	// logBodies enables logging of request and response bodies
+	logBodies bool
+}
+
+func (t *logTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := req.Header.Get(correlationHeader)
+	if id == "" {
+		id = newCorrelationID()
+		req = req.Clone(req.Context())
+		req.Header.Set(correlationHeader, id)
+	}
+
+	if t.logBodies && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := ioutil.ReadAll(io.LimitReader(body, maxLoggedBody))
+			body.Close()
+			log.Printf("[TRACE] Shaken Fist API request %s %s %s body: %s",
+				id, req.Method, req.URL.Path, redactBody(data))
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	latency := time.Since(start).Round(time.Millisecond)
+
+	if err != nil {
+		log.Printf("[DEBUG] Shaken Fist API request %s %s %s failed "+
+			"after %v: %v", id, req.Method, req.URL.Path, latency, err)
+		return resp, err
+	}
+
+	log.Printf("[DEBUG] Shaken Fist API request %s %s %s returned %d in %v",
+		id, req.Method, req.URL.Path, resp.StatusCode, latency)
+
+	if t.logBodies {
+		data, readErr := ioutil.ReadAll(
+			io.LimitReader(resp.Body, maxLoggedBody))
+		resp.Body = &multiReadCloser{
+			Reader: io.MultiReader(bytes.NewReader(data), resp.Body),
+			Closer: resp.Body,
+		}
+		if readErr == nil {
+			log.Printf("[TRACE] Shaken Fist API response %s body: %s",
+				id, redactBody(data))
+		}
+	}
+
+	return resp, nil
+}
+
+// multiReadCloser reads from Reader and closes Closer.
+type multiReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// newCorrelationID returns a random identifier for a request.
+func newCorrelationID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// redactBody returns the body for logging with the values of secret fields
+// replaced. Bodies that are not JSON are not logged.
+func redactBody(data []byte) string {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return "<empty>"
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return "<" + http.DetectContentType(data) + " body not logged>"
+	}
+
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(redactValue(v)); err != nil {
+		return "<not logged>"
+	}
+	return strings.TrimSpace(out.String())
+}
+
+// redactValue replaces the values of secret fields within decoded JSON.
+func redactValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, inner := range val {
+			if redactedFields[strings.ToLower(k)] {
+				val[k] = "<redacted>"
+			} else {
+				val[k] = redactValue(inner)
+			}
+		}
+	case []interface{}:
+		for i, inner := range val {
+			val[i] = redactValue(inner)
+		}
+	}
+	return v
+}
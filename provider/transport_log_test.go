@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestUnitLogTransport(t *testing.T) {
+	var gotID string
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			gotID = r.Header.Get(correlationHeader)
+			w.Write([]byte(`{"access_token": "secrettoken"}`))
+		}))
+	defer server.Close()
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	c := &http.Client{Transport: &logTransport{
+		next:      baseTransport.Clone(),
+		logBodies: true,
+	}}
+	resp, err := c.Post(server.URL+"/auth", "application/json",
+		strings.NewReader(`{"namespace": "system", "key": "secretkey"}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	// The response body is still readable by the client
+	if string(body) != `{"access_token": "secrettoken"}` {
+		t.Errorf("Response body changed: %s", body)
+	}
+
+	out := logged.String()
+	if gotID == "" || !strings.Contains(out, gotID) {
+		t.Errorf("Correlation ID %q not sent and logged: %s", gotID, out)
+	}
+	if !strings.Contains(out, "POST /auth returned 200") {
+		t.Errorf("Request summary not logged: %s", out)
+	}
+	if !strings.Contains(out, `"namespace":"system"`) {
+		t.Errorf("Request body not logged: %s", out)
+	}
+	if strings.Contains(out, "secretkey") ||
+		strings.Contains(out, "secrettoken") {
+		t.Errorf("Secrets logged: %s", out)
+	}
+}
+
+func TestUnitRedactBody(t *testing.T) {
+	tests := map[string]string{
+		``:                                      "<empty>",
+		`{"user_data": "I2Nsb3VkLWNvbmZpZw=="}`: `{"user_data":"<redacted>"}`,
+		`[{"name": "jump", "Key": "x"}]`:        `[{"Key":"<redacted>","name":"jump"}]`,
+		`not json`:                              "<text/plain; charset=utf-8 body not logged>",
+	}
+
+	for body, expected := range tests {
+		if actual := redactBody([]byte(body)); actual != expected {
+			t.Errorf("redactBody(%q) = %q, expected %q",
+				body, actual, expected)
+		}
+	}
+}
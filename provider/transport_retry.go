@@ -0,0 +1,144 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+// retryPolicy is the provider retry and backoff configuration for requests to
+// the Shaken Fist API.
+type retryPolicy struct {
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// retryTransport retries failed requests with exponential backoff and full
+// jitter. Idempotent requests are retried on connection errors and gateway
+// or availability errors. Other requests, such as creating an instance, are
+// only retried if the connection to the server could not be made, as the
+// server cannot have acted upon them.
+type retryTransport struct {
+	policy retryPolicy
+	next   http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+
+		if attempt >= t.policy.maxRetries || !t.shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		// The request body must be sent again
+		if req.Body != nil {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			req.Body = body
+		}
+
+		if resp != nil {
+			log.Printf("[DEBUG] Shaken Fist API %s %s returned %d, retrying",
+				req.Method, req.URL.Path, resp.StatusCode)
+			resp.Body.Close()
+		} else {
+			log.Printf("[DEBUG] Shaken Fist API %s %s failed, retrying: %v",
+				req.Method, req.URL.Path, err)
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(t.backoff(attempt)):
+		}
+	}
+}
+
+// shouldRetry reports whether the request should be retried.
+func (t *retryTransport) shouldRetry(req *http.Request, resp *http.Response,
+	err error) bool {
+
+	if req.Context().Err() != nil {
+		return false
+	}
+
+	if err != nil {
+		if isDialError(err) {
+			return true
+		}
+		return isIdempotent(req.Method)
+	}
+
+	if !isIdempotent(req.Method) {
+		return false
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff returns the random delay before the retry following attempt.
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	ceiling := t.policy.maxBackoff
+	if attempt < 32 {
+		exp := t.policy.minBackoff << uint(attempt)
+		if exp > 0 && exp < ceiling {
+			ceiling = exp
+		}
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// isIdempotent reports whether repeating a request with the HTTP method has
+// the same effect as making it once.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions,
+		http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isDialError reports whether the error occurred while connecting to the
+// server, before the request was sent.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// validateRetryPolicy checks the retry settings are usable.
+func validateRetryPolicy(p retryPolicy) error {
+	if p.maxRetries < 0 {
+		return fmt.Errorf("max_retries must not be negative")
+	}
+	if p.minBackoff < 0 || p.maxBackoff < 0 {
+		return fmt.Errorf("Retry backoff must not be negative")
+	}
+	if p.minBackoff > p.maxBackoff {
+		return fmt.Errorf(
+			"retry_min_backoff must not be greater than retry_max_backoff")
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testRetryTransport(maxRetries int) *retryTransport {
+	return &retryTransport{
+		policy: retryPolicy{
+			maxRetries: maxRetries,
+			minBackoff: time.Millisecond,
+			maxBackoff: 5 * time.Millisecond,
+		},
+		next: baseTransport.Clone(),
+	}
+}
+
+func TestUnitRetryTransport(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+	defer server.Close()
+
+	c := &http.Client{Transport: testRetryTransport(3)}
+
+	// Idempotent requests are retried
+	resp, err := c.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls != 3 {
+		t.Errorf("GET returned %d after %d calls, expected 200 after 3",
+			resp.StatusCode, calls)
+	}
+
+	// Non-idempotent requests are not retried after reaching the server
+	atomic.StoreInt32(&calls, 0)
+	resp, err = c.Post(server.URL, "application/json",
+		strings.NewReader(`{"name": "jump"}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || calls != 1 {
+		t.Errorf("POST returned %d after %d calls, expected 503 after 1",
+			resp.StatusCode, calls)
+	}
+
+	// Retries are limited
+	atomic.StoreInt32(&calls, -100)
+	resp, err = c.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || calls != -96 {
+		t.Errorf("GET returned %d after %d calls, expected 503 after 4",
+			resp.StatusCode, calls+100)
+	}
+}
+
+func TestUnitRetryTransportDialError(t *testing.T) {
+	// Find a port with nothing listening on it
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	tr := testRetryTransport(2)
+	var calls int32
+	tr.next = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return baseTransport.RoundTrip(req)
+	})
+
+	c := &http.Client{Transport: tr}
+	_, err = c.Post("http://"+addr, "application/json",
+		strings.NewReader(`{"name": "jump"}`))
+	if err == nil {
+		t.Fatalf("Request to closed port should fail")
+	}
+	if calls != 3 {
+		t.Errorf("POST with connection error made %d calls, expected 3",
+			calls)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestUnitRetryBackoff(t *testing.T) {
+	tr := testRetryTransport(10)
+	tr.policy.minBackoff = time.Second
+	tr.policy.maxBackoff = 4 * time.Second
+
+	for attempt := 0; attempt < 40; attempt++ {
+		b := tr.backoff(attempt)
+		if b < 0 || b > tr.policy.maxBackoff {
+			t.Errorf("Backoff for attempt %d is %v", attempt, b)
+		}
+	}
+}
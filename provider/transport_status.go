@@ -0,0 +1,42 @@
+package provider
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// statusTransport returns failed Shaken Fist API responses as an *apiError
+// holding the HTTP status, so that errors are classified from the response
+// rather than from the text of the client library error. Unauthorized
+// responses are passed on unchanged, as the client library handles them by
+// authenticating again.
+type statusTransport struct {
+	next http.RoundTripper
+}
+
+func (t *statusTransport) RoundTrip(req *http.Request) (*http.Response,
+	error) {
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	kind := statusKind(resp.StatusCode)
+	if kind != errorNotFound && kind != errorConflict && kind != errorServer {
+		return resp, nil
+	}
+
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+
+	return nil, &apiError{
+		Kind:   kind,
+		Status: resp.StatusCode,
+		Err: fmt.Errorf("%s %s returned status %d: %s", req.Method,
+			req.URL.Path, resp.StatusCode, strings.TrimSpace(string(body))),
+	}
+}
@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUnitStatusTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/instances/gone":
+				writeError(w, http.StatusNotFound, "instance not found")
+			case "/auth":
+				writeError(w, http.StatusUnauthorized, "bad key")
+			case "/networks":
+				writeError(w, http.StatusInternalServerError, "broken")
+			default:
+				writeJSON(w, http.StatusOK, nil)
+			}
+		}))
+	defer server.Close()
+
+	c := &http.Client{Transport: &statusTransport{
+		next: baseTransport.Clone(),
+	}}
+
+	_, err := c.Get(server.URL + "/instances/gone")
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("404 response returned %v, not an API error", err)
+	}
+	if apiErr.Kind != errorNotFound || apiErr.Status != 404 {
+		t.Errorf("404 response classified as %v with status %d",
+			apiErr.Kind, apiErr.Status)
+	}
+	if !strings.Contains(apiErr.Error(), "instance not found") {
+		t.Errorf("Error %q does not hold the response body", apiErr)
+	}
+	if !isNotFound(err) {
+		t.Errorf("404 response is not reported as not found")
+	}
+
+	if _, err := c.Get(server.URL + "/networks"); !isRetryable(err) {
+		t.Errorf("500 response is not retryable: %v", err)
+	}
+
+	// The client library authenticates again on unauthorized responses
+	resp, err := c.Get(server.URL + "/auth")
+	if err != nil {
+		t.Fatalf("401 response returned an error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("401 response has status %d", resp.StatusCode)
+	}
+
+	resp, err = c.Get(server.URL + "/instances")
+	if err != nil {
+		t.Fatalf("200 response returned an error: %v", err)
+	}
+	resp.Body.Close()
+}